@@ -3,6 +3,7 @@ package xapi
 import (
 	"context"
 	"errors"
+	"net/http"
 	"net/url"
 	"sync"
 	"time"
@@ -11,13 +12,19 @@ import (
 )
 
 const (
-	SYNC_PORT_REAL              = 5112
-	SYNC_PORT_DEMO              = 5124
-	SYNC_WEBSOCKET_ADDRESS_REAL = "wss://ws.xtb.com/real"
-	SYNC_WEBSOCKET_ADDRESS_DEMO = "wss://ws.xtb.com/demo"
-	API_ADDRESS_BASE            = "https://xapi.xtb.com"
+	SYNC_PORT_REAL                = 5112
+	SYNC_PORT_DEMO                = 5124
+	SYNC_WEBSOCKET_ADDRESS_REAL   = "wss://ws.xtb.com/real"
+	SYNC_WEBSOCKET_ADDRESS_DEMO   = "wss://ws.xtb.com/demo"
+	STREAM_PORT_REAL              = 5113
+	STREAM_PORT_DEMO              = 5125
+	STREAM_WEBSOCKET_ADDRESS_REAL = "wss://ws.xtb.com/realStream"
+	STREAM_WEBSOCKET_ADDRESS_DEMO = "wss://ws.xtb.com/demoStream"
+	API_ADDRESS_BASE              = "https://xapi.xtb.com"
 )
 
+var ErrClientClosed = errors.New("client closed")
+
 type ClientMode string
 
 var (
@@ -26,32 +33,60 @@ var (
 )
 
 type Client struct {
-	conn       *websocket.Conn
-	userID     int
-	password   string
-	url        *url.URL
-	cancelPing context.CancelFunc
+	conn        *connection
+	credentials CredentialProvider
+	appID       string
+	appName     string
+	url         *url.URL
+	streamURL   *url.URL
+	cancelPing  context.CancelFunc
+	done        <-chan struct{}
+
+	dialer          *websocket.Dialer
+	header          http.Header
+	reconnectPolicy *ReconnectPolicy
+	limiter         *rateLimiter
+	idempotency     IdempotencyPolicy
+	pingInterval    time.Duration
+	streamURLSet    bool // streamURL was given with WithStreamURL
+	stateHandler    func(StateEvent)
+	loggedIn        bool
+	streamSessionID string
+
+	sem chan struct{} // held while logging in or reconnecting, guards loggedIn and streamSessionID
+	cm  sync.Mutex    // guards conn
+
+	am      sync.Mutex    // guards active, idle and streams
+	active  int           // calls in flight
+	idle    chan struct{} // set by Shutdown, closed once no call is in flight
+	streams map[*StreamClient]struct{}
 
-	m sync.Mutex
+	stm          sync.Mutex // guards state and healthyState
+	state        ConnectionState
+	healthyState ConnectionState // state to return to from StateDegraded
 }
 
 type optFunc func(*Client) error
 
 func WithUserCredentials(userID int, password string) optFunc {
 	return func(c *Client) error {
-		c.userID = userID
-		c.password = password
+		c.credentials = StaticCredentials{
+			UserID:   userID,
+			Password: password,
+		}
 		return nil
 	}
 }
 
 func WithMode(mode ClientMode) optFunc {
 	return func(c *Client) error {
-		var rawURL string
+		var rawURL, rawStreamURL string
 		if mode == ClientModeDemo {
 			rawURL = SYNC_WEBSOCKET_ADDRESS_DEMO
+			rawStreamURL = STREAM_WEBSOCKET_ADDRESS_DEMO
 		} else if mode == ClientModeReal {
 			rawURL = SYNC_WEBSOCKET_ADDRESS_REAL
+			rawStreamURL = STREAM_WEBSOCKET_ADDRESS_REAL
 		}
 
 		u, err := url.Parse(rawURL)
@@ -59,6 +94,16 @@ func WithMode(mode ClientMode) optFunc {
 			return err
 		}
 		c.url = u
+
+		if c.streamURLSet {
+			return nil
+		}
+
+		su, err := url.Parse(rawStreamURL)
+		if err != nil {
+			return err
+		}
+		c.streamURL = su
 		return nil
 	}
 }
@@ -70,14 +115,50 @@ func WithURL(rawURL string) optFunc {
 			return err
 		}
 		c.url = u
+
+		// The stream address of an earlier WithMode does not belong to this URL.
+		if !c.streamURLSet {
+			c.streamURL = nil
+		}
+		return nil
+	}
+}
+
+// WithPingInterval sets how often ping is sent to keep the connection alive. Failed pings are reported to the handler set with WithStateHandler.
+func WithPingInterval(d time.Duration) optFunc {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("invalid ping interval")
+		}
+
+		c.pingInterval = d
+		return nil
+	}
+}
+
+// WithStreamURL sets the address of the streaming socket. By default it is derived from the mode or from the URL given with WithURL by appending "Stream" to its path, whichever was given last.
+func WithStreamURL(rawURL string) optFunc {
+	return func(c *Client) error {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return err
+		}
+		c.streamURL = u
+		c.streamURLSet = true
 		return nil
 	}
 }
 
 func NewClient(ctx context.Context, opts ...optFunc) (*Client, error) {
 	ctx, cancel := context.WithCancel(ctx)
+	dialer := *websocket.DefaultDialer
 	c := &Client{
-		cancelPing: cancel,
+		dialer:       &dialer,
+		cancelPing:   cancel,
+		done:         ctx.Done(),
+		sem:          make(chan struct{}, 1),
+		streams:      make(map[*StreamClient]struct{}),
+		pingInterval: 5 * time.Minute,
 	}
 
 	for _, opt := range opts {
@@ -91,22 +172,28 @@ func NewClient(ctx context.Context, opts ...optFunc) (*Client, error) {
 		return nil, errors.New("url is required")
 	}
 
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.Dial(c.url.String(), nil)
+	c.setState(StateConnecting, nil)
+	conn, err := c.dial(ctx)
 	if err != nil {
 		return nil, err
 	}
+	c.setConnection(conn)
+	c.setState(StateConnected, nil)
 
-	c.conn = conn
-
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(c.pingInterval)
 	go func() {
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				c.ping()
+				// A ping refused because Shutdown is in progress says nothing about the connection.
+				err := c.ping(ctx)
+				if err == nil {
+					c.recoverState()
+				} else if ctx.Err() == nil && !errors.Is(err, ErrClientClosed) {
+					c.setState(StateDegraded, err)
+				}
 			}
 		}
 	}()
@@ -114,16 +201,87 @@ func NewClient(ctx context.Context, opts ...optFunc) (*Client, error) {
 	return c, nil
 }
 
+func (c *Client) dial(ctx context.Context) (*connection, error) {
+	ws, err := dialWebsocket(ctx, c.dialer, c.url, c.header)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConnection(ws, c.limiter, c.connectionFailed), nil
+}
+
+func (c *Client) connection() *connection {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	return c.conn
+}
+
+// setConnection replaces the current connection, unless the client was closed in the meantime.
+func (c *Client) setConnection(conn *connection) error {
+	c.cm.Lock()
+	defer c.cm.Unlock()
+
+	select {
+	case <-c.done:
+		conn.close()
+		return ErrClientClosed
+	default:
+	}
+
+	c.conn = conn
+	return nil
+}
+
 func (c *Client) Login() error {
-	return login(c)
+	return c.LoginContext(context.Background())
+}
+
+// LoginContext is like Login, but stops waiting for the response when ctx is done.
+func (c *Client) LoginContext(ctx context.Context) error {
+	err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer c.end()
+
+	err = c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	err = login(ctx, c, c.connection())
+	if err != nil {
+		return err
+	}
+
+	c.setState(StateLoggedIn, nil)
+	return nil
+}
+
+func (c *Client) lock(ctx context.Context) error {
+	select {
+	case c.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) unlock() {
+	<-c.sem
 }
 
 func (c *Client) Close() {
+	c.setState(StateClosed, nil)
 	c.cancelPing()
-	c.conn.Close()
+
+	c.cm.Lock()
+	defer c.cm.Unlock()
+	c.conn.close()
 }
 
-func (c *Client) ping() error {
-	_, err := getSync[any, any](c, "ping", nil)
+func (c *Client) ping(ctx context.Context) error {
+	_, err := getSync[any, any](ctx, c, "ping", nil)
 	return err
 }
@@ -0,0 +1,585 @@
+package xapi_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/voxelost/xapi"
+)
+
+// flakyServer answers every command with a successful response, but drops the connection instead of answering the commands listed in drop, once per command. Responses to the commands in delay are sent late.
+type flakyServer struct {
+	url string
+
+	m         sync.Mutex
+	drop      map[string]bool
+	delay     map[string]time.Duration
+	logins    int
+	logouts   int
+	lastLogin map[string]any // arguments of the last login
+}
+
+func newFlakyServer(t *testing.T, drop ...string) *flakyServer {
+	fs := &flakyServer{
+		drop:  make(map[string]bool),
+		delay: make(map[string]time.Duration),
+	}
+	for _, command := range drop {
+		fs.drop[command] = true
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(fs.handle))
+	fs.url = "ws://" + strings.TrimPrefix(server.URL, "http://")
+	t.Cleanup(server.Close)
+	return fs
+}
+
+func (fs *flakyServer) handle(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	for {
+		var cmd map[string]any
+		err := conn.ReadJSON(&cmd)
+		if err != nil {
+			return
+		}
+
+		fs.m.Lock()
+		command, _ := cmd["command"].(string)
+		drop := fs.drop[command]
+		delete(fs.drop, command)
+		delay := fs.delay[command]
+		if command == "login" {
+			fs.logins++
+			fs.lastLogin, _ = cmd["arguments"].(map[string]any)
+		}
+		if command == "logout" {
+			fs.logouts++
+		}
+		fs.m.Unlock()
+
+		if drop {
+			return
+		}
+
+		time.Sleep(delay)
+		err = conn.WriteJSON(map[string]any{
+			"status":     true,
+			"returnData": map[string]any{"version": "2.5.0", "order": 1, "time": time.Now().UnixMilli()},
+			"customTag":  cmd["customTag"],
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (fs *flakyServer) loginCount() int {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+	return fs.logins
+}
+
+func (fs *flakyServer) logoutCount() int {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+	return fs.logouts
+}
+
+func (fs *flakyServer) lastLoginArguments() map[string]any {
+	fs.m.Lock()
+	defer fs.m.Unlock()
+	return fs.lastLogin
+}
+
+func TestReconnect(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t, "getVersion", "tradeTransaction")
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(fs.url), xapi.WithReconnect(xapi.ReconnectPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2.5.0" {
+		t.Errorf("unexpected version %q", version)
+	}
+	if fs.loginCount() != 2 {
+		t.Errorf("expected 2 logins, got %d", fs.loginCount())
+	}
+
+	_, err = c.CreateTradeTransaction(xapi.TradeTransactionInput{Symbol: "EURUSD"})
+	if err == nil {
+		t.Error("expected tradeTransaction not to be retried")
+	}
+
+	_, err = c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.loginCount() != 3 {
+		t.Errorf("expected 3 logins, got %d", fs.loginCount())
+	}
+}
+
+func TestNoReconnectByDefault(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t, "getVersion")
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(fs.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.GetVersion()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	_, err = c.GetVersion()
+	if err == nil {
+		t.Error("expected the connection to stay broken")
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t)
+	fs.delay["getServerTime"] = 200 * time.Millisecond
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(fs.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.GetServerTimeContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	version, err := c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "2.5.0" {
+		t.Errorf("unexpected version %q", version)
+	}
+}
+
+func TestAbandonedCallIsUnregistered(t *testing.T) {
+	t.Parallel()
+
+	// Never answers getServerTime and answers everything else without a customTag.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var cmd map[string]any
+			err := conn.ReadJSON(&cmd)
+			if err != nil {
+				return
+			}
+
+			if cmd["command"] == "getServerTime" {
+				continue
+			}
+
+			err = conn.WriteJSON(map[string]any{
+				"status":     true,
+				"returnData": map[string]any{"version": "2.5.0"},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL("ws://"+strings.TrimPrefix(server.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.GetServerTimeContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	version, err := c.GetVersionContext(ctx)
+	if err != nil {
+		t.Fatalf("expected the response not to be routed to the abandoned call, got %v", err)
+	}
+	if version != "2.5.0" {
+		t.Errorf("unexpected version %q", version)
+	}
+}
+
+func TestConcurrentCalls(t *testing.T) {
+	t.Parallel()
+
+	// Answers each pair of commands in reverse order, echoing the requested volume as margin.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			var pair [2]map[string]any
+			for i := range pair {
+				err := conn.ReadJSON(&pair[i])
+				if err != nil {
+					return
+				}
+			}
+
+			for i := len(pair) - 1; i >= 0; i-- {
+				arguments, _ := pair[i]["arguments"].(map[string]any)
+				err := conn.WriteJSON(map[string]any{
+					"status":     true,
+					"returnData": map[string]any{"margin": arguments["volume"]},
+					"customTag":  pair[i]["customTag"],
+				})
+				if err != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL("ws://"+strings.TrimPrefix(server.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for _, volume := range []float64{0.1, 0.2, 0.3, 0.4} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			margin, err := c.GetMarginTrade("EURUSD", volume)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if margin != volume {
+				t.Errorf("expected %v, got %v", volume, margin)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t)
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(fs.url), xapi.WithRateLimit(50*time.Millisecond, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	start := time.Now()
+	for range 4 {
+		_, err := c.GetVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	elapsed := time.Since(start)
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("expected the last two commands to be delayed, took %v", elapsed)
+	}
+
+	stats := c.RateLimitStats()
+	if stats.Requests != 4 || stats.Delayed != 2 || stats.MaxWait > 50*time.Millisecond || stats.TotalWait < 50*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	c := ms.login(context.Background())
+
+	s, err := c.Stream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("expected the stream to be closed")
+	}
+
+	_, err = c.GetVersion()
+	if err != xapi.ErrClientClosed {
+		t.Errorf("expected ErrClientClosed, got %v", err)
+	}
+}
+
+func TestShutdownWaitsForCalls(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t)
+	fs.delay["getServerTime"] = 100 * time.Millisecond
+
+	events := make(chan xapi.StateEvent, 64)
+	c, err := xapi.NewClient(context.Background(),
+		xapi.WithURL(fs.url),
+		xapi.WithPingInterval(10*time.Millisecond),
+		xapi.WithStateHandler(func(e xapi.StateEvent) {
+			select {
+			case events <- e:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := c.GetServerTime()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	err = c.Shutdown(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected the call in flight to finish, got %v", err)
+		}
+	default:
+		t.Error("expected Shutdown to wait for the call in flight")
+	}
+
+	for len(events) > 0 {
+		e := <-events
+		if e.State == xapi.StateDegraded {
+			t.Errorf("expected no degraded state while shutting down, got %v", e.Err)
+		}
+	}
+}
+
+func TestStateEvents(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t, "ping")
+
+	events := make(chan xapi.StateEvent, 16)
+	c, err := xapi.NewClient(context.Background(),
+		xapi.WithURL(fs.url),
+		xapi.WithPingInterval(20*time.Millisecond),
+		xapi.WithReconnect(xapi.ReconnectPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+		xapi.WithStateHandler(func(e xapi.StateEvent) {
+			events <- e
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(state xapi.ConnectionState, withErr bool) {
+		t.Helper()
+		e := receive(t, events)
+		if e.State != state || (e.Err != nil) != withErr {
+			t.Fatalf("expected %v event, got %v (%v)", state, e.State, e.Err)
+		}
+	}
+
+	expect(xapi.StateConnecting, false)
+	expect(xapi.StateConnected, false)
+	expect(xapi.StateLoggedIn, false)
+	expect(xapi.StateDegraded, true)
+	expect(xapi.StateConnecting, false)
+	expect(xapi.StateLoggedIn, false)
+
+	if c.State() != xapi.StateLoggedIn {
+		t.Errorf("expected state %v, got %v", xapi.StateLoggedIn, c.State())
+	}
+
+	c.Close()
+	expect(xapi.StateClosed, false)
+}
+
+func TestTLSAndHeaders(t *testing.T) {
+	t.Parallel()
+	fs := &flakyServer{
+		drop:  make(map[string]bool),
+		delay: make(map[string]time.Duration),
+	}
+
+	headers := make(chan string, 1)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Get("X-Client")
+		fs.handle(w, r)
+	}))
+	defer server.Close()
+
+	url := "wss://" + strings.TrimPrefix(server.URL, "https://")
+	_, err := xapi.NewClient(context.Background(), xapi.WithURL(url))
+	if err == nil {
+		t.Fatal("expected the untrusted certificate to be rejected")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	c, err := xapi.NewClient(context.Background(),
+		xapi.WithURL(url),
+		xapi.WithDialer(&websocket.Dialer{ReadBufferSize: 1024}),
+		xapi.WithTLSConfig(&tls.Config{RootCAs: roots}),
+		xapi.WithHandshakeTimeout(time.Second),
+		xapi.WithHeader(http.Header{"X-Client": {"test"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if header := <-headers; header != "test" {
+		t.Errorf("expected header %q, got %q", "test", header)
+	}
+
+	_, err = c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCredentialProvider(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t, "getVersion")
+
+	var logins int
+	provider := xapi.CredentialProviderFunc(func(context.Context) (xapi.Credentials, error) {
+		logins++
+		return xapi.Credentials{UserID: 42, Password: fmt.Sprintf("secret%d", logins)}, nil
+	})
+
+	c, err := xapi.NewClient(context.Background(),
+		xapi.WithURL(fs.url),
+		xapi.WithCredentialProvider(provider),
+		xapi.WithApp("", "tests"),
+		xapi.WithReconnect(xapi.ReconnectPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arguments := fs.lastLoginArguments()
+	if arguments["userId"] != 42.0 || arguments["password"] != "secret1" || arguments["appName"] != "tests" {
+		t.Errorf("unexpected login arguments %v", arguments)
+	}
+	if _, ok := arguments["appId"]; ok {
+		t.Error("expected an empty appId to be omitted")
+	}
+
+	_, err = c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	arguments = fs.lastLoginArguments()
+	if arguments["password"] != "secret2" {
+		t.Errorf("expected the provider to be consulted again on reconnect, got %v", arguments)
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	err := os.WriteFile(path, []byte(`{"userId": 42, "password": "secret"}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := xapi.FileCredentials(path).Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds != (xapi.Credentials{UserID: 42, Password: "secret"}) {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	_, err = xapi.FileCredentials(filepath.Join(t.TempDir(), "missing.json")).Credentials(context.Background())
+	if err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -1,49 +1,177 @@
 package xapi
 
-func getSync[T, R any](c *Client, command string, data T) (R, error) {
-	c.m.Lock()
-	defer c.m.Unlock()
+import (
+	"context"
+	"encoding/json"
+	"sync"
 
-	var r R
-	err := writeJSON(c, command, data)
-	if err != nil {
-		return r, err
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+type callResult struct {
+	resp response[json.RawMessage]
+	err  error
+}
+
+type call struct {
+	id uuid.UUID
+	ch chan callResult
+}
+
+// connection is a websocket with a reader goroutine that routes responses to the calls waiting for them by their customTag. Responses without a customTag go to the oldest waiting call, as the server answers in order.
+type connection struct {
+	ws      *websocket.Conn
+	limiter *rateLimiter             // shared by all connections of a client, may be nil
+	onFail  func(*connection, error) // called once the reader stopped, may be nil
+
+	wm    sync.Mutex // guards writes to ws
+	m     sync.Mutex // guards calls and err
+	calls []*call
+	err   error // set once the reader stopped
+}
+
+func newConnection(ws *websocket.Conn, limiter *rateLimiter, onFail func(*connection, error)) *connection {
+	conn := &connection{
+		ws:      ws,
+		limiter: limiter,
+		onFail:  onFail,
 	}
 
-	err = getResponse(c, &r)
-	return r, err
+	go conn.readLoop()
+	return conn
 }
 
-func writeJSON[T any](c *Client, command string, data T) error {
-	cmd := newCommand(command, data)
-	return c.conn.WriteJSON(cmd)
+func (conn *connection) close() error {
+	return conn.ws.Close()
 }
 
-func getResponse[T any](c *Client, res *T) error {
-	respBody := response[T]{}
-	err := c.conn.ReadJSON(&respBody)
-	if err != nil {
-		return err
+func (conn *connection) readLoop() {
+	for {
+		var resp response[json.RawMessage]
+		err := conn.ws.ReadJSON(&resp)
+		if err != nil {
+			conn.fail(err)
+			return
+		}
+
+		cl := conn.take(resp.MessageID)
+		if cl != nil {
+			cl.ch <- callResult{resp: resp}
+		}
+	}
+}
+
+// fail stops routing, reports err and hands it to every waiting call.
+func (conn *connection) fail(err error) {
+	conn.m.Lock()
+	calls := conn.calls
+	conn.calls = nil
+	conn.err = err
+	conn.m.Unlock()
+
+	// Report the failure before the calls see it, as they may start reconnecting.
+	if conn.onFail != nil {
+		conn.onFail(conn, err)
+	}
+
+	for _, cl := range calls {
+		cl.ch <- callResult{err: err}
 	}
+}
+
+func (conn *connection) register(id uuid.UUID) (*call, error) {
+	conn.m.Lock()
+	defer conn.m.Unlock()
+
+	if conn.err != nil {
+		return nil, conn.err
+	}
+
+	cl := &call{
+		id: id,
+		ch: make(chan callResult, 1),
+	}
+	conn.calls = append(conn.calls, cl)
+	return cl, nil
+}
+
+func (conn *connection) take(id uuid.UUID) *call {
+	conn.m.Lock()
+	defer conn.m.Unlock()
 
-	if !respBody.Status {
-		var errorCode, errorDescription string
-		if respBody.ErrorCode != nil {
-			errorCode = *respBody.ErrorCode
+	for i, cl := range conn.calls {
+		if id == uuid.Nil || cl.id == id {
+			conn.calls = append(conn.calls[:i], conn.calls[i+1:]...)
+			return cl
 		}
-		if respBody.ErrorDescription != nil {
-			errorDescription = *respBody.ErrorDescription
+	}
+
+	return nil
+}
+
+func (conn *connection) writeJSON(v any) error {
+	conn.wm.Lock()
+	defer conn.wm.Unlock()
+	return conn.ws.WriteJSON(v)
+}
+
+// send sends the command on conn and waits for its response. When ctx is done first, the call is unregistered and send returns ctx.Err() right away; the command may still be executed by the server and its response is dropped.
+func send[T any](ctx context.Context, conn *connection, command string, data T) (response[json.RawMessage], error) {
+	err := conn.limiter.wait(ctx)
+	if err != nil {
+		return response[json.RawMessage]{}, err
+	}
+
+	cmd := newCommand(command, data)
+	cl, err := conn.register(cmd.MessageID)
+	if err != nil {
+		return response[json.RawMessage]{}, err
+	}
+
+	err = conn.writeJSON(cmd)
+	if err != nil {
+		conn.take(cmd.MessageID)
+		return response[json.RawMessage]{}, err
+	}
+
+	select {
+	case res := <-cl.ch:
+		if res.err != nil {
+			return response[json.RawMessage]{}, res.err
 		}
+		return res.resp, res.resp.err()
+	case <-ctx.Done():
+		conn.take(cmd.MessageID)
+		return response[json.RawMessage]{}, ctx.Err()
+	}
+}
 
-		return ApiError{
-			Code:    errorCode,
-			Message: errorDescription,
+// getSync sends the command on the current connection and decodes the returned data. Any number of calls can wait for their responses at the same time.
+func getSync[T, R any](ctx context.Context, c *Client, command string, data T) (R, error) {
+	var r R
+	err := c.begin()
+	if err != nil {
+		return r, err
+	}
+	defer c.end()
+
+	conn := c.connection()
+	resp, err := send(ctx, conn, command, data)
+	if err != nil && c.shouldReconnect(err) {
+		rerr := c.reconnect(ctx, conn)
+		if rerr == nil && isIdempotent(command) {
+			resp, err = send(ctx, c.connection(), command, data)
 		}
 	}
 
-	if respBody.ReturnData != nil {
-		*res = *respBody.ReturnData
+	if err != nil {
+		return r, err
 	}
 
-	return nil
+	if resp.ReturnData != nil {
+		err = json.Unmarshal(*resp.ReturnData, &r)
+	}
+
+	return r, err
 }
@@ -0,0 +1,93 @@
+package xapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+type Credentials struct {
+	UserID   int    `json:"userId"`
+	Password string `json:"password"`
+}
+
+// CredentialProvider returns the credentials to log in with. It is consulted on every login, including the ones made when reconnecting, so the credentials it returns may change over time.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials always returns the same credentials.
+type StaticCredentials Credentials
+
+func (s StaticCredentials) Credentials(context.Context) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// EnvCredentials reads the user id and the password from the environment variables userIDVar and passwordVar.
+func EnvCredentials(userIDVar, passwordVar string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		userID, err := strconv.Atoi(os.Getenv(userIDVar))
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid user id in %s: %w", userIDVar, err)
+		}
+
+		password, ok := os.LookupEnv(passwordVar)
+		if !ok {
+			return Credentials{}, fmt.Errorf("%s is not set", passwordVar)
+		}
+
+		return Credentials{
+			UserID:   userID,
+			Password: password,
+		}, nil
+	})
+}
+
+// FileCredentials reads the credentials from the JSON file at path, e.g. {"userId": 123, "password": "secret"}.
+func FileCredentials(path string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return Credentials{}, err
+		}
+
+		var creds Credentials
+		err = json.Unmarshal(data, &creds)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid credentials file %s: %w", path, err)
+		}
+
+		return creds, nil
+	})
+}
+
+// WithCredentialProvider sets where the credentials are taken from on every login.
+func WithCredentialProvider(provider CredentialProvider) optFunc {
+	return func(c *Client) error {
+		if provider == nil {
+			return errors.New("credential provider is required")
+		}
+
+		c.credentials = provider
+		return nil
+	}
+}
+
+// WithApp sets the optional application id and name sent on login.
+func WithApp(appID, appName string) optFunc {
+	return func(c *Client) error {
+		c.appID = appID
+		c.appName = appName
+		return nil
+	}
+}
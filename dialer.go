@@ -0,0 +1,79 @@
+package xapi
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WithDialer sets the dialer used for the main and the streaming connection, e.g. to change buffer sizes or enable compression. The dialer is copied, options given after it such as WithTLSConfig modify the copy.
+func WithDialer(dialer *websocket.Dialer) optFunc {
+	return func(c *Client) error {
+		if dialer == nil {
+			return errors.New("dialer is required")
+		}
+
+		d := *dialer
+		c.dialer = &d
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used when dialing, e.g. with pinned root certificates.
+func WithTLSConfig(config *tls.Config) optFunc {
+	return func(c *Client) error {
+		c.dialer.TLSClientConfig = config
+		return nil
+	}
+}
+
+// WithProxy dials through the proxy at proxyURL. Both http and socks5 proxies are supported.
+func WithProxy(proxyURL string) optFunc {
+	return func(c *Client) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return err
+		}
+
+		c.dialer.Proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithHandshakeTimeout sets how long the websocket handshake may take.
+func WithHandshakeTimeout(d time.Duration) optFunc {
+	return func(c *Client) error {
+		if d <= 0 {
+			return errors.New("invalid handshake timeout")
+		}
+
+		c.dialer.HandshakeTimeout = d
+		return nil
+	}
+}
+
+// WithHeader adds header to the handshake request of every connection.
+func WithHeader(header http.Header) optFunc {
+	return func(c *Client) error {
+		if c.header == nil {
+			c.header = make(http.Header)
+		}
+
+		for key, values := range header {
+			for _, value := range values {
+				c.header.Add(key, value)
+			}
+		}
+		return nil
+	}
+}
+
+func dialWebsocket(ctx context.Context, dialer *websocket.Dialer, u *url.URL, header http.Header) (*websocket.Conn, error) {
+	ws, _, err := dialer.DialContext(ctx, u.String(), header)
+	return ws, err
+}
@@ -1,10 +1,103 @@
 package xapi
 
+import "errors"
+
 type ApiError struct {
 	Code    string
 	Message string
 }
 
 func (e ApiError) Error() string {
-	return e.Message
+	if e.Code == "" {
+		return e.Message
+	}
+
+	return e.Code + ": " + e.Message
+}
+
+// Is reports whether target is an ApiError with the same code, so that errors.Is(err, ErrMarketClosed) matches whatever message the server sent.
+func (e ApiError) Is(target error) bool {
+	t, ok := target.(ApiError)
+	return ok && t.Code == e.Code
+}
+
+// Error codes documented for the API. Compare against them with errors.Is.
+var (
+	ErrInvalidPrice                 = ApiError{Code: "BE001", Message: "Invalid price"}
+	ErrInvalidStops                 = ApiError{Code: "BE002", Message: "Invalid StopLoss or TakeProfit"}
+	ErrInvalidVolume                = ApiError{Code: "BE003", Message: "Invalid volume"}
+	ErrLoginDisabled                = ApiError{Code: "BE004", Message: "Login disabled"}
+	ErrInvalidLogin                 = ApiError{Code: "BE005", Message: "Invalid login or password"}
+	ErrMarketClosed                 = ApiError{Code: "BE006", Message: "Market for instrument is closed"}
+	ErrMismatchedParameters         = ApiError{Code: "BE007", Message: "Mismatched parameters"}
+	ErrModificationDenied           = ApiError{Code: "BE008", Message: "Modification is denied"}
+	ErrNotEnoughMoney               = ApiError{Code: "BE009", Message: "Not enough money on account to perform trade"}
+	ErrOffQuotes                    = ApiError{Code: "BE010", Message: "Off quotes"}
+	ErrOppositePositionsProhibited  = ApiError{Code: "BE011", Message: "Opposite positions prohibited"}
+	ErrShortPositionsProhibited     = ApiError{Code: "BE012", Message: "Short positions prohibited"}
+	ErrPriceChanged                 = ApiError{Code: "BE013", Message: "Price has changed"}
+	ErrRequestTooFrequent           = ApiError{Code: "BE014", Message: "Request too frequent"}
+	ErrTooManyTradeRequests         = ApiError{Code: "BE016", Message: "Too many trade requests"}
+	ErrTradeRequestLimit            = ApiError{Code: "BE017", Message: "Too many trade requests"}
+	ErrTradingDisabled              = ApiError{Code: "BE018", Message: "Trading on instrument disabled"}
+	ErrTradingTimeout               = ApiError{Code: "BE019", Message: "Trading timeout"}
+	ErrSymbolNotForAccount          = ApiError{Code: "BE094", Message: "Symbol does not exist for given account"}
+	ErrSymbolNotTradable            = ApiError{Code: "BE095", Message: "Account cannot trade on given symbol"}
+	ErrPendingOrderClose            = ApiError{Code: "BE096", Message: "Pending order cannot be closed. Pending order must be deleted"}
+	ErrOrderAlreadyClosed           = ApiError{Code: "BE097", Message: "Cannot close already closed order"}
+	ErrNoSuchTransaction            = ApiError{Code: "BE098", Message: "No such transaction"}
+	ErrUnknownSymbol                = ApiError{Code: "BE101", Message: "Unknown instrument symbol"}
+	ErrUnknownTransactionType       = ApiError{Code: "BE102", Message: "Unknown transaction type"}
+	ErrNotLoggedIn                  = ApiError{Code: "BE103", Message: "User is not logged"}
+	ErrUnknownMethod                = ApiError{Code: "BE104", Message: "Method does not exist"}
+	ErrIncorrectPeriod              = ApiError{Code: "BE105", Message: "Incorrect period given"}
+	ErrMissingData                  = ApiError{Code: "BE106", Message: "Missing data"}
+	ErrIncorrectCommandFormat       = ApiError{Code: "BE110", Message: "Incorrect command format"}
+	ErrSymbolNotFound               = ApiError{Code: "BE115", Message: "Symbol does not exist"}
+	ErrInvalidToken                 = ApiError{Code: "BE117", Message: "Invalid token"}
+	ErrAlreadyLoggedIn              = ApiError{Code: "BE118", Message: "User already logged"}
+	ErrInvalidParameters            = ApiError{Code: "EX000", Message: "Invalid parameters"}
+	ErrInternal                     = ApiError{Code: "EX001", Message: "Internal error"}
+	ErrMessageRejected              = ApiError{Code: "EX002", Message: "Message rejected"}
+	ErrRequestTimedOut              = ApiError{Code: "EX003", Message: "Internal error, request timed out"}
+	ErrLoginIncorrect               = ApiError{Code: "EX004", Message: "Login or password incorrect"}
+	ErrSystemOverloaded             = ApiError{Code: "EX005", Message: "System overloaded"}
+	ErrNoAccess                     = ApiError{Code: "EX006", Message: "No access"}
+	ErrInvalidUserPassword          = ApiError{Code: "EX007", Message: "userPasswordCheck: Invalid login or password"}
+	ErrConnectionLimitReached       = ApiError{Code: "EX008", Message: "You have reached the connection limit"}
+	ErrDataLimitPotentiallyExceeded = ApiError{Code: "EX009", Message: "Data limit potentially exceeded. Please narrow your request range"}
+	ErrDataLimitExceeded            = ApiError{Code: "EX010", Message: "Data limit exceeded. Please narrow your request range"}
+)
+
+var retryableCodes = map[string]bool{
+	ErrOffQuotes.Code:            true,
+	ErrPriceChanged.Code:         true,
+	ErrRequestTooFrequent.Code:   true,
+	ErrTooManyTradeRequests.Code: true,
+	ErrTradeRequestLimit.Code:    true,
+	ErrTradingTimeout.Code:       true,
+	ErrInternal.Code:             true,
+	ErrRequestTimedOut.Code:      true,
+	ErrSystemOverloaded.Code:     true,
+}
+
+var authCodes = map[string]bool{
+	ErrLoginDisabled.Code:       true,
+	ErrInvalidLogin.Code:        true,
+	ErrNotLoggedIn.Code:         true,
+	ErrInvalidToken.Code:        true,
+	ErrLoginIncorrect.Code:      true,
+	ErrInvalidUserPassword.Code: true,
+}
+
+// IsRetryable reports whether err is an API error which may not happen again when the same command is sent later, e.g. a changed price or a request sent too frequently.
+func IsRetryable(err error) bool {
+	var apiErr ApiError
+	return errors.As(err, &apiErr) && retryableCodes[apiErr.Code]
+}
+
+// IsAuthError reports whether err is an API error caused by invalid credentials or a missing session.
+func IsAuthError(err error) bool {
+	var apiErr ApiError
+	return errors.As(err, &apiErr) && authCodes[apiErr.Code]
 }
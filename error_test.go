@@ -0,0 +1,51 @@
+package xapi_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/voxelost/xapi"
+)
+
+func TestApiErrorIs(t *testing.T) {
+	err := fmt.Errorf("getSymbol: %w", xapi.ApiError{Code: "BE006", Message: "Market closed for EURUSD"})
+
+	if !errors.Is(err, xapi.ErrMarketClosed) {
+		t.Error("expected the error to match ErrMarketClosed")
+	}
+	if errors.Is(err, xapi.ErrInvalidPrice) {
+		t.Error("expected the error not to match ErrInvalidPrice")
+	}
+	if err.Error() != "getSymbol: BE006: Market closed for EURUSD" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+		auth      bool
+	}{
+		{xapi.ApiError{Code: "BE013", Message: "Price has changed"}, true, false},
+		{xapi.ApiError{Code: "BE017"}, true, false},
+		{xapi.ApiError{Code: "BE005"}, false, true},
+		{xapi.ApiError{Code: "BE103"}, false, true},
+		{xapi.ApiError{Code: "EX005"}, true, false},
+		{xapi.ApiError{Code: "EX007"}, false, true},
+		{xapi.ErrConnectionLimitReached, false, false},
+		{xapi.ErrNotEnoughMoney, false, false},
+		{errors.New("connection reset"), false, false},
+		{nil, false, false},
+	}
+
+	for _, test := range tests {
+		if xapi.IsRetryable(test.err) != test.retryable {
+			t.Errorf("IsRetryable(%v) = %v", test.err, !test.retryable)
+		}
+		if xapi.IsAuthError(test.err) != test.auth {
+			t.Errorf("IsAuthError(%v) = %v", test.err, !test.auth)
+		}
+	}
+}
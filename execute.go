@@ -0,0 +1,135 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrTradeRejected = errors.New("trade rejected")
+
+	// ErrTradeRecordUnavailable is returned together with an accepted result whose trade record could not be fetched. The order was executed and must not be sent again.
+	ErrTradeRecordUnavailable = errors.New("trade accepted, but its record could not be fetched")
+)
+
+// Polling of the transaction status starts after minStatusBackoff and slows down to maxStatusBackoff.
+const (
+	minStatusBackoff = 50 * time.Millisecond
+	maxStatusBackoff = time.Second
+)
+
+// TradeResult is the outcome of a transaction executed with ExecuteTrade.
+type TradeResult struct {
+	OrderID     int                    // Order number returned by tradeTransaction
+	Status      TradeStatus            // Final status of the transaction
+	Message     string                 // Reason given by the server, if any
+	Price       float64                // Price the transaction was executed at, 0 if it was not accepted or is not known
+	Transaction TradeTransactionStatus // Last status returned by tradeTransactionStatus
+	Trade       *Trade                 // Trade record of the resulting position or pending order, nil if none is left open
+}
+
+// ExecuteTrade sends input and polls the status of the transaction with backoff until it is final. The calls are subject to the rate limit set with WithRateLimit. An accepted transaction is returned with its fill price and the resulting trade record; a rejected one together with an error wrapping ErrTradeRejected. If the trade record cannot be fetched, the accepted result is returned without it, together with an error wrapping ErrTradeRecordUnavailable.
+func (c *Client) ExecuteTrade(ctx context.Context, input TradeTransactionInput) (TradeResult, error) {
+	orderID, err := c.CreateTradeTransactionContext(ctx, input)
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	status, err := c.awaitTradeTransaction(ctx, orderID)
+	result := TradeResult{
+		OrderID:     orderID,
+		Status:      TradeStatus(status.RequestStatus),
+		Transaction: status,
+	}
+	if status.Message != nil {
+		result.Message = *status.Message
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if result.Status != TradeStatusAccepted {
+		message := result.Message
+		if message == "" {
+			message = "no reason given"
+		}
+		return result, fmt.Errorf("%w: order %d: %s", ErrTradeRejected, orderID, message)
+	}
+
+	trades, err := c.GetTradeRecordsContext(ctx, []int{orderID})
+	if err != nil {
+		result.Price = fillPrice(input, result)
+		return result, fmt.Errorf("%w: order %d: %w", ErrTradeRecordUnavailable, orderID, err)
+	}
+
+	for i := range trades {
+		if trades[i].OrderID == orderID || trades[i].Order2ID == orderID {
+			result.Trade = &trades[i]
+			break
+		}
+	}
+
+	result.Price = fillPrice(input, result)
+	return result, nil
+}
+
+// awaitTradeTransaction polls the status of the transaction until it is final. Retryable API errors are polled through. On error, the last status received is returned.
+func (c *Client) awaitTradeTransaction(ctx context.Context, orderID int) (TradeTransactionStatus, error) {
+	var status TradeTransactionStatus
+	backoff := minStatusBackoff
+	for {
+		s, err := c.GetTradeTransactionStatusContext(ctx, orderID)
+		if err == nil {
+			status = s
+			if TradeStatus(status.RequestStatus).IsFinal() {
+				return status, nil
+			}
+		} else if !IsRetryable(err) {
+			return status, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, maxStatusBackoff)
+	}
+}
+
+// fillPrice returns the price an accepted transaction was executed at. Opening orders take it from the resulting trade, others from the side of the market they were executed on. A pending order without a trade record has no known price.
+func fillPrice(input TradeTransactionInput, result TradeResult) float64 {
+	switch input.Type {
+	case OrderTypeOpen:
+		if result.Trade != nil {
+			return result.Trade.OpenPrice
+		}
+		if isPending(input.Command) {
+			return 0
+		}
+		if result.Transaction.Price != 0 {
+			return result.Transaction.Price
+		}
+
+		// A buy order is opened at the ask price and a sell order at the bid price.
+		if isBuy(input.Command) {
+			return result.Transaction.Ask
+		}
+		return result.Transaction.Bid
+	case OrderTypeClose:
+		if result.Transaction.Price != 0 {
+			return result.Transaction.Price
+		}
+
+		// A long position is closed at the bid price and a short one at the ask price.
+		if isBuy(input.Command) {
+			return result.Transaction.Bid
+		}
+		return result.Transaction.Ask
+	}
+
+	return input.Price
+}
@@ -0,0 +1,118 @@
+package xapi_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+	"github.com/voxelost/xapi/xapitest"
+)
+
+func TestExecuteTrade(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+
+	result, err := c.ExecuteTrade(context.Background(), xapi.TradeTransactionInput{
+		Command: xapi.BuyCommand,
+		Price:   1.1002,
+		Symbol:  "EURUSD",
+		Type:    xapi.OrderTypeOpen,
+		Volume:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.OrderID != 101 || result.Status != xapi.TradeStatusAccepted {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Trade == nil || result.Trade.Order2ID != 101 {
+		t.Fatalf("expected the trade record of order 101, got %+v", result.Trade)
+	}
+	if result.Price != 1.1001 {
+		t.Errorf("expected the fill price of the trade record, got %v", result.Price)
+	}
+
+	ts.m.Lock()
+	polls := ts.polls[101]
+	ts.m.Unlock()
+	if polls != 2 {
+		t.Errorf("expected the status to be polled until it is final, got %d polls", polls)
+	}
+}
+
+func TestExecuteTradeRetriesStatus(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+
+	polls := 0
+	ts.Handle("tradeTransactionStatus", func(req xapitest.Request) (any, error) {
+		polls++
+		if polls < 3 {
+			return nil, xapi.ErrRequestTooFrequent
+		}
+		return map[string]any{"order": 101, "requestStatus": xapi.TradeStatusAccepted, "bid": 1.1, "ask": 1.1002}, nil
+	})
+
+	start := time.Now()
+	result, err := c.ExecuteTrade(context.Background(), xapi.TradeTransactionInput{
+		Command: xapi.BuyCommand,
+		Order:   7,
+		Symbol:  "EURUSD",
+		Type:    xapi.OrderTypeClose,
+		Volume:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if polls != 3 {
+		t.Errorf("expected 3 polls, got %d", polls)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the polls to back off, took %v", elapsed)
+	}
+	if result.Price != 1.1 {
+		t.Errorf("expected a long position to be closed at the bid price, got %v", result.Price)
+	}
+}
+
+func TestExecuteTradeContext(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+	ts.Respond("tradeTransactionStatus", map[string]any{"order": 101, "requestStatus": xapi.TradeStatusPending})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := c.ExecuteTrade(ctx, xapi.TradeTransactionInput{Symbol: "EURUSD", Volume: 1})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if result.OrderID != 101 || result.Status != xapi.TradeStatusPending {
+		t.Errorf("expected the pending order to be reported, got %+v", result)
+	}
+}
+
+func TestExecuteTradeRecordUnavailable(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+	ts.Fail("getTradeRecords", xapi.ErrInternal)
+	ts.Respond("tradeTransactionStatus", map[string]any{"order": 101, "requestStatus": xapi.TradeStatusAccepted, "bid": 1.1001, "ask": 1.1003})
+
+	result, err := c.ExecuteTrade(context.Background(), xapi.TradeTransactionInput{
+		Command: xapi.BuyCommand,
+		Price:   1.1002,
+		Symbol:  "EURUSD",
+		Type:    xapi.OrderTypeOpen,
+		Volume:  1,
+	})
+	if !errors.Is(err, xapi.ErrTradeRecordUnavailable) {
+		t.Fatalf("expected ErrTradeRecordUnavailable, got %v", err)
+	}
+	if result.OrderID != 101 || result.Status != xapi.TradeStatusAccepted || result.Trade != nil || result.Price != 1.1003 {
+		t.Errorf("expected the accepted result filled at the ask price, got %+v", result)
+	}
+}
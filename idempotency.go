@@ -0,0 +1,146 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyPolicy describes how ExecuteTradeOnce looks for an order submitted earlier with the same client order id. Zero fields take the defaults.
+type IdempotencyPolicy struct {
+	Window      time.Duration // How far back the trade history is searched
+	MaxAttempts int           // How many times an order is submitted while its submissions are lost
+	GracePeriod time.Duration // How long a lost submission is searched for before the order is submitted again
+}
+
+const (
+	DefaultIdempotencyWindow = 24 * time.Hour
+	DefaultMaxSubmitAttempts = 3
+	DefaultSubmitGracePeriod = 5 * time.Second
+)
+
+// WithIdempotency sets the policy used by FindTrade and ExecuteTradeOnce.
+func WithIdempotency(policy IdempotencyPolicy) optFunc {
+	return func(c *Client) error {
+		if policy.Window < 0 || policy.MaxAttempts < 0 || policy.GracePeriod < 0 {
+			return errors.New("invalid idempotency policy")
+		}
+
+		c.idempotency = policy
+		return nil
+	}
+}
+
+func (p IdempotencyPolicy) withDefaults() IdempotencyPolicy {
+	if p.Window == 0 {
+		p.Window = DefaultIdempotencyWindow
+	}
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultMaxSubmitAttempts
+	}
+	if p.GracePeriod == 0 {
+		p.GracePeriod = DefaultSubmitGracePeriod
+	}
+	return p
+}
+
+// NewClientOrderID returns a random client order id for ExecuteTradeOnce.
+func NewClientOrderID() string {
+	return uuid.NewString()
+}
+
+// FindTrade returns the open or closed trade whose custom comment is clientOrderID, or nil if there is none. Closed trades are searched within the window of the idempotency policy (see WithIdempotency).
+func (c *Client) FindTrade(ctx context.Context, clientOrderID string) (*Trade, error) {
+	trades, err := c.GetTradesContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := c.recentTradesHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range append(trades, history...) {
+		if t.CustomComment == clientOrderID {
+			return &t, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// recentTradesHistory returns the trades closed within the window of the idempotency policy.
+func (c *Client) recentTradesHistory(ctx context.Context) ([]Trade, error) {
+	now := time.Now()
+	return c.GetTradesHistoryContext(ctx, now.Add(-c.idempotency.withDefaults().Window), now)
+}
+
+// ExecuteTradeOnce is like ExecuteTrade, but opens at most one order for clientOrderID, which is stored as the order's custom comment. If a trade with that comment already exists, it is returned instead of submitting the order. A submission lost with the connection is searched for during the grace period of WithIdempotency, and submitted again only if it is not found. Lost submissions are only retried by clients created with WithReconnect.
+func (c *Client) ExecuteTradeOnce(ctx context.Context, clientOrderID string, input TradeTransactionInput) (TradeResult, error) {
+	if clientOrderID == "" {
+		return TradeResult{}, errors.New("client order id is required")
+	}
+
+	if input.Type != OrderTypeOpen {
+		return TradeResult{}, errors.New("only opening orders can be submitted once")
+	}
+
+	input.CustomComment = clientOrderID
+
+	policy := c.idempotency.withDefaults()
+	trade, err := c.FindTrade(ctx, clientOrderID)
+	for attempt := 1; ; attempt++ {
+		if err != nil {
+			return TradeResult{}, err
+		}
+
+		if trade != nil {
+			return TradeResult{
+				OrderID: trade.OrderID,
+				Status:  TradeStatusAccepted,
+				Price:   trade.OpenPrice,
+				Trade:   trade,
+			}, nil
+		}
+
+		result, submitErr := c.ExecuteTrade(ctx, input)
+
+		// Only a submission which got no order number may be lost, anything else is the answer of the server.
+		if submitErr == nil || result.OrderID != 0 || !c.shouldReconnect(submitErr) {
+			return result, submitErr
+		}
+
+		trade, err = c.awaitLostTrade(ctx, clientOrderID, policy.GracePeriod)
+		if err == nil && trade == nil && attempt >= policy.MaxAttempts {
+			return TradeResult{}, submitErr
+		}
+	}
+}
+
+// awaitLostTrade searches the trades for clientOrderID with backoff until grace has passed, because an order still being processed by the server is not listed yet.
+func (c *Client) awaitLostTrade(ctx context.Context, clientOrderID string, grace time.Duration) (*Trade, error) {
+	deadline := time.Now().Add(grace)
+	backoff := minStatusBackoff
+	for {
+		trade, err := c.FindTrade(ctx, clientOrderID)
+		if err != nil || trade != nil {
+			return trade, err
+		}
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(wait, backoff)):
+		}
+
+		backoff = min(backoff*2, maxStatusBackoff)
+	}
+}
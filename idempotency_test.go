@@ -0,0 +1,76 @@
+package xapi_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/voxelost/xapi"
+)
+
+func TestExecuteTradeOnce(t *testing.T) {
+	t.Parallel()
+
+	input := xapi.TradeTransactionInput{
+		Command: xapi.BuyCommand,
+		Price:   1.1002,
+		Symbol:  "EURUSD",
+		Type:    xapi.OrderTypeOpen,
+		Volume:  1,
+	}
+
+	tests := []struct {
+		name       string
+		lostBefore int
+		lostAfter  int
+		hidden     int
+		orderID    int
+	}{
+		{"submitted", 0, 0, 0, 101},
+		{"lost before execution", 1, 0, 0, 101},
+		{"lost after execution", 0, 1, 0, 1101},
+		{"lost while processed", 0, 1, 3, 1101},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			ts, c := newTradingServer(t)
+			ts.lostBefore = test.lostBefore
+			ts.lostAfter = test.lostAfter
+			ts.hidden = test.hidden
+
+			id := xapi.NewClientOrderID()
+			result, err := c.ExecuteTradeOnce(context.Background(), id, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.OrderID != test.orderID || result.Status != xapi.TradeStatusAccepted || result.Trade == nil || result.Trade.CustomComment != id {
+				t.Errorf("unexpected result %+v", result)
+			}
+
+			ts.m.Lock()
+			trades := len(ts.trades)
+			ts.m.Unlock()
+			if trades != 1 {
+				t.Errorf("expected 1 order, got %d", trades)
+			}
+
+			// Retrying with the same id returns the existing order.
+			result, err = c.ExecuteTradeOnce(context.Background(), id, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Trade == nil || result.Trade.CustomComment != id {
+				t.Errorf("expected the existing trade, got %+v", result)
+			}
+
+			ts.m.Lock()
+			trades = len(ts.trades)
+			ts.m.Unlock()
+			if trades != 1 {
+				t.Errorf("expected the retry not to submit the order again, got %d orders", trades)
+			}
+		})
+	}
+}
@@ -212,3 +212,56 @@ type UserData struct {
 	SpreadType         *string `json:"spreadType,omitempty"`
 	TrailingStop       bool    `json:"trailingStop"`
 }
+
+type StreamCandle struct {
+	Close                 float64 `json:"close"`     // Close price in base currency
+	CandleStartTime       int64   `json:"ctm"`       // Candle start time in CET time zone (Central European Time)
+	CandleStartTimeString string  `json:"ctmString"` // String representation of the ctm field
+	High                  float64 `json:"high"`      // Highest value in the given period in base currency
+	Low                   float64 `json:"low"`       // Lowest value in the given period in base currency
+	Open                  float64 `json:"open"`      // Open price in base currency
+	QuoteID               int     `json:"quoteId"`   // Source of price
+	Symbol                string  `json:"symbol"`    // Symbol
+	Volume                float64 `json:"vol"`       // Volume in lots
+}
+
+type StreamTrade struct {
+	Trade
+	State string `json:"state"` // Trade state, should be used for detecting pending order's cancellation
+	Type  int    `json:"type"`  // Order type
+}
+
+type StreamTradeStatus struct {
+	CustomComment string  `json:"customComment"` // The value the customer may provide in order to retrieve it later.
+	Message       *string `json:"message"`       // Can be null
+	Order         int     `json:"order"`         // Unique order number
+	Price         float64 `json:"price"`         // Price in base currency
+	RequestStatus int     `json:"requestStatus"` // Request status code
+}
+
+type StreamBalance struct {
+	Balance     float64 `json:"balance"`     // Balance in account currency
+	Credit      float64 `json:"credit"`      // Credit in account currency
+	Equity      float64 `json:"equity"`      // Sum of balance and all profits in account currency
+	Margin      float64 `json:"margin"`      // Margin requirements
+	MarginFree  float64 `json:"marginFree"`  // Free margin
+	MarginLevel float64 `json:"marginLevel"` // Margin level percentage
+}
+
+type StreamProfit struct {
+	Order    int     `json:"order"`    // Order number
+	Order2   int     `json:"order2"`   // Transaction ID
+	Position int     `json:"position"` // Position number
+	Profit   float64 `json:"profit"`   // Profit in account currency
+}
+
+type StreamNews struct {
+	Body  string `json:"body"`  // Body
+	Key   string `json:"key"`   // News key
+	Time  int64  `json:"time"`  // Time
+	Title string `json:"title"` // News title
+}
+
+type StreamKeepAlive struct {
+	Timestamp int64 `json:"timestamp"` // Current timestamp
+}
@@ -1,42 +1,39 @@
 package xapi
 
-func login(c *Client) error {
+import "context"
+
+// login logs in on conn. It must be called with the client locked.
+func login(ctx context.Context, c *Client, conn *connection) error {
 	type loginInput struct {
 		UserId   int    `json:"userId"`
 		Password string `json:"password"`
+		AppId    string `json:"appId,omitempty"`
+		AppName  string `json:"appName,omitempty"`
 	}
 
-	err := c.conn.WriteJSON(command[loginInput]{
-		Command: "login",
-		Arguments: loginInput{
-			UserId:   c.userID,
-			Password: c.password,
-		},
-	})
-	if err != nil {
-		return err
+	var creds Credentials
+	if c.credentials != nil {
+		var err error
+		creds, err = c.credentials.Credentials(ctx)
+		if err != nil {
+			return err
+		}
 	}
 
-	respBody := response[any]{}
-	err = c.conn.ReadJSON(&respBody)
+	resp, err := send(ctx, conn, "login", loginInput{
+		UserId:   creds.UserID,
+		Password: creds.Password,
+		AppId:    c.appID,
+		AppName:  c.appName,
+	})
 	if err != nil {
 		return err
 	}
 
-	if !respBody.Status {
-		var errorCode, errorDescription string
-		if respBody.ErrorCode != nil {
-			errorCode = *respBody.ErrorCode
-		}
-		if respBody.ErrorDescription != nil {
-			errorDescription = *respBody.ErrorDescription
-		}
-
-		return ApiError{
-			Code:    errorCode,
-			Message: errorDescription,
-		}
+	c.loggedIn = true
+	if resp.StreamSessionID != nil {
+		c.streamSessionID = *resp.StreamSessionID
 	}
 
-	return err
+	return nil
 }
@@ -1,6 +1,7 @@
 package xapi
 
 import (
+	"context"
 	"time"
 
 	"github.com/voxelost/xapi/internal"
@@ -27,7 +28,12 @@ type Calendar struct {
 
 // GetCalendar returns an array of Calendar objects.
 func (c *Client) GetCalendar() ([]Calendar, error) {
-	calendars, err := getSync[any, []internal.Calendar](c, "getCalendar", nil)
+	return c.GetCalendarContext(context.Background())
+}
+
+// GetCalendarContext is like GetCalendar, but stops waiting for the response when ctx is done.
+func (c *Client) GetCalendarContext(ctx context.Context) ([]Calendar, error) {
+	calendars, err := getSync[any, []internal.Calendar](ctx, c, "getCalendar", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +104,11 @@ request charts of 5 minutes period, for 3 months time span, back from now;
 response: you are guaranteed to get 1 month of 5 minutes charts; because, 5 minutes period charts are not accessible 2 months and 3 months back from now.
 */
 func (c *Client) GetChartLast(period ChartInfoRecordPeriod, start time.Time, symbol string) (ChartInfo, error) {
+	return c.GetChartLastContext(context.Background(), period, start, symbol)
+}
+
+// GetChartLastContext is like GetChartLast, but stops waiting for the response when ctx is done.
+func (c *Client) GetChartLastContext(ctx context.Context, period ChartInfoRecordPeriod, start time.Time, symbol string) (ChartInfo, error) {
 	type chartLastRecordInputInfo struct {
 		Period ChartInfoRecordPeriod `json:"period"` // Period code
 		Start  int64                 `json:"start"`  // Start of chart block (rounded down to the nearest interval and excluding)
@@ -108,7 +119,7 @@ func (c *Client) GetChartLast(period ChartInfoRecordPeriod, start time.Time, sym
 		Info chartLastRecordInputInfo `json:"info"`
 	}
 
-	res, err := getSync[chartLastRecordInput, internal.ChartInfo](c, "getChartLastRequest", chartLastRecordInput{
+	res, err := getSync[chartLastRecordInput, internal.ChartInfo](ctx, c, "getChartLastRequest", chartLastRecordInput{
 		Info: chartLastRecordInputInfo{
 			Period: period,
 			Start:  start.UnixMilli(),
@@ -153,6 +164,11 @@ PERIOD_D1 --- 13 month, and earlier on
 Note, that specific PERIOD_ is the lowest (i.e. the most detailed) period, accessible in listed range. For instance, in months range <1-7) you can access periods: PERIOD_M30, PERIOD_H1, PERIOD_H4, PERIOD_D1, PERIOD_W1, PERIOD_MN1. Specific data ranges availability is guaranteed, however those ranges may be wider, e.g.: PERIOD_M1 may be accessible for 1.5 months back from now, where 1.0 months is guaranteed.
 */
 func (c *Client) GetChartRange(period ChartInfoRecordPeriod, start, end time.Time, symbol string) (ChartInfo, error) {
+	return c.GetChartRangeContext(context.Background(), period, start, end, symbol)
+}
+
+// GetChartRangeContext is like GetChartRange, but stops waiting for the response when ctx is done.
+func (c *Client) GetChartRangeContext(ctx context.Context, period ChartInfoRecordPeriod, start, end time.Time, symbol string) (ChartInfo, error) {
 	type chartRangeRecordInputInfo struct {
 		Period ChartInfoRecordPeriod `json:"period"`          // Period code
 		Start  int64                 `json:"start"`           // Start of chart block (rounded down to the nearest interval and excluding)
@@ -172,7 +188,7 @@ func (c *Client) GetChartRange(period ChartInfoRecordPeriod, start, end time.Tim
 		Info chartRangeRecordInputInfo `json:"info"`
 	}
 
-	res, err := getSync[chartRangeRecordInput, internal.ChartInfo](c, "getChartRangeRequest", chartRangeRecordInput{
+	res, err := getSync[chartRangeRecordInput, internal.ChartInfo](ctx, c, "getChartRangeRequest", chartRangeRecordInput{
 		Info: chartRangeRecordInputInfo{
 			Period: period,
 			Start:  start.UnixMilli(),
@@ -211,12 +227,17 @@ type CommissionDef struct {
 
 // GetCommissionDef returns calculation of commission and rate of exchange. The value is calculated as expected value, and therefore might not be perfectly accurate.
 func (c *Client) GetCommissionDef(symbol string, volume float64) (CommissionDef, error) {
+	return c.GetCommissionDefContext(context.Background(), symbol, volume)
+}
+
+// GetCommissionDefContext is like GetCommissionDef, but stops waiting for the response when ctx is done.
+func (c *Client) GetCommissionDefContext(ctx context.Context, symbol string, volume float64) (CommissionDef, error) {
 	type commissionDefInput struct {
 		Symbol string  `json:"symbol"`
 		Volume float64 `json:"volume"`
 	}
 
-	res, err := getSync[commissionDefInput, internal.CommissionDef](c, "getCommissionDef", commissionDefInput{
+	res, err := getSync[commissionDefInput, internal.CommissionDef](ctx, c, "getCommissionDef", commissionDefInput{
 		Symbol: symbol,
 		Volume: volume,
 	})
@@ -243,7 +264,12 @@ type UserData struct {
 
 // GetCurrentUserData returns information about account currency, and account leverage.
 func (c *Client) GetCurrentUserData() (UserData, error) {
-	res, err := getSync[any, internal.UserData](c, "getCurrentUserData", nil)
+	return c.GetCurrentUserDataContext(context.Background())
+}
+
+// GetCurrentUserDataContext is like GetCurrentUserData, but stops waiting for the response when ctx is done.
+func (c *Client) GetCurrentUserDataContext(ctx context.Context) (UserData, error) {
+	res, err := getSync[any, internal.UserData](ctx, c, "getCurrentUserData", nil)
 	if err != nil {
 		return UserData{}, err
 	}
@@ -271,7 +297,12 @@ type MarginLevel struct {
 
 // GetMarginLevel eturns various account indicators.
 func (c *Client) GetMarginLevel() (MarginLevel, error) {
-	res, err := getSync[any, internal.MarginLevel](c, "getMarginLevel", nil)
+	return c.GetMarginLevelContext(context.Background())
+}
+
+// GetMarginLevelContext is like GetMarginLevel, but stops waiting for the response when ctx is done.
+func (c *Client) GetMarginLevelContext(ctx context.Context) (MarginLevel, error) {
+	res, err := getSync[any, internal.MarginLevel](ctx, c, "getMarginLevel", nil)
 	if err != nil {
 		return MarginLevel{}, err
 	}
@@ -289,12 +320,17 @@ func (c *Client) GetMarginLevel() (MarginLevel, error) {
 
 // GetMarginTrade returns expected margin for given instrument and volume. The value is calculated as expected margin value, and therefore might not be perfectly accurate.
 func (c *Client) GetMarginTrade(symbol string, volume float64) (float64, error) {
+	return c.GetMarginTradeContext(context.Background(), symbol, volume)
+}
+
+// GetMarginTradeContext is like GetMarginTrade, but stops waiting for the response when ctx is done.
+func (c *Client) GetMarginTradeContext(ctx context.Context, symbol string, volume float64) (float64, error) {
 	type getMarginTradeInput struct {
 		Symbol string  `json:"symbol"`
 		Volume float64 `json:"volume"`
 	}
 
-	marginTrade, err := getSync[getMarginTradeInput, internal.MarginTrade](c, "getMarginTrade", getMarginTradeInput{
+	marginTrade, err := getSync[getMarginTradeInput, internal.MarginTrade](ctx, c, "getMarginTrade", getMarginTradeInput{
 		Symbol: symbol,
 		Volume: volume,
 	})
@@ -317,12 +353,17 @@ type NewsTopic struct {
 
 // GetNews returns news from trading server which were sent within specified period of time.
 func (c *Client) GetNews(start, end time.Time) ([]NewsTopic, error) {
+	return c.GetNewsContext(context.Background(), start, end)
+}
+
+// GetNewsContext is like GetNews, but stops waiting for the response when ctx is done.
+func (c *Client) GetNewsContext(ctx context.Context, start, end time.Time) ([]NewsTopic, error) {
 	type getNewsInput struct {
 		Start int64 `json:"start"`
 		End   int64 `json:"end"`
 	}
 
-	news, err := getSync[getNewsInput, []internal.NewsTopic](c, "getNews", getNewsInput{
+	news, err := getSync[getNewsInput, []internal.NewsTopic](ctx, c, "getNews", getNewsInput{
 		Start: start.UnixMilli(),
 		End:   end.UnixMilli(),
 	})
@@ -361,6 +402,11 @@ var (
 
 // GetProfitCalculation calculates estimated profit for given deal data Should be used for calculator-like apps only. Profit for opened transactions should be taken from server, due to higher precision of server calculation.
 func (c *Client) GetProfitCalculation(symbol string, cmd TradeCommand, volume, openPrice, closePrice float64) (float64, error) {
+	return c.GetProfitCalculationContext(context.Background(), symbol, cmd, volume, openPrice, closePrice)
+}
+
+// GetProfitCalculationContext is like GetProfitCalculation, but stops waiting for the response when ctx is done.
+func (c *Client) GetProfitCalculationContext(ctx context.Context, symbol string, cmd TradeCommand, volume, openPrice, closePrice float64) (float64, error) {
 	type getProfitCalculationInput struct {
 		ClosePrice float64      `json:"closePrice"`
 		Command    TradeCommand `json:"cmd"`
@@ -369,7 +415,7 @@ func (c *Client) GetProfitCalculation(symbol string, cmd TradeCommand, volume, o
 		Volume     float64      `json:"volume"`
 	}
 
-	res, err := getSync[getProfitCalculationInput, internal.ProfitCalculation](c, "getProfitCalculation", getProfitCalculationInput{
+	res, err := getSync[getProfitCalculationInput, internal.ProfitCalculation](ctx, c, "getProfitCalculation", getProfitCalculationInput{
 		Symbol:     symbol,
 		Command:    cmd,
 		Volume:     volume,
@@ -381,12 +427,17 @@ func (c *Client) GetProfitCalculation(symbol string, cmd TradeCommand, volume, o
 
 // GetServerTime returns current time on trading server.
 func (c *Client) GetServerTime() (time.Time, error) {
+	return c.GetServerTimeContext(context.Background())
+}
+
+// GetServerTimeContext is like GetServerTime, but stops waiting for the response when ctx is done.
+func (c *Client) GetServerTimeContext(ctx context.Context) (time.Time, error) {
 	type serverTime struct {
 		Time       int64  `json:"time"`
 		TimeString string `json:"timeString"`
 	}
 
-	res, err := getSync[any, serverTime](c, "getServerTime", nil)
+	res, err := getSync[any, serverTime](ctx, c, "getServerTime", nil)
 	if err != nil {
 		return time.Time{}, err
 	}
@@ -407,7 +458,12 @@ type StepRule struct {
 
 // GetStepRules returns a list of step rules for DMAs.
 func (c *Client) GetStepRules() ([]StepRule, error) {
-	res, err := getSync[any, []internal.StepRule](c, "getStepRules", nil)
+	return c.GetStepRulesContext(context.Background())
+}
+
+// GetStepRulesContext is like GetStepRules, but stops waiting for the response when ctx is done.
+func (c *Client) GetStepRulesContext(ctx context.Context) ([]StepRule, error) {
+	res, err := getSync[any, []internal.StepRule](ctx, c, "getStepRules", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -506,7 +562,12 @@ type Symbol struct {
 
 // GetAllSymbols returns array of all symbols available for the user.
 func (c *Client) GetAllSymbols() ([]Symbol, error) {
-	symbols, err := getSync[interface{}, []internal.Symbol](c, "getAllSymbols", nil)
+	return c.GetAllSymbolsContext(context.Background())
+}
+
+// GetAllSymbolsContext is like GetAllSymbols, but stops waiting for the response when ctx is done.
+func (c *Client) GetAllSymbolsContext(ctx context.Context) ([]Symbol, error) {
+	symbols, err := getSync[interface{}, []internal.Symbol](ctx, c, "getAllSymbols", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -571,11 +632,16 @@ func (c *Client) GetAllSymbols() ([]Symbol, error) {
 
 // GetSymbol returns information about symbol available for the user.
 func (c *Client) GetSymbol(ticker string) (Symbol, error) {
+	return c.GetSymbolContext(context.Background(), ticker)
+}
+
+// GetSymbolContext is like GetSymbol, but stops waiting for the response when ctx is done.
+func (c *Client) GetSymbolContext(ctx context.Context, ticker string) (Symbol, error) {
 	type getSymbolInput struct {
 		Symbol string `json:"symbol"`
 	}
 
-	res, err := getSync[getSymbolInput, internal.Symbol](c, "getSymbol", getSymbolInput{
+	res, err := getSync[getSymbolInput, internal.Symbol](ctx, c, "getSymbol", getSymbolInput{
 		Symbol: ticker,
 	})
 	if err != nil {
@@ -660,6 +726,11 @@ type TickRecord struct {
 
 // GetTickPrices returns array of current quotations for given symbols, only quotations that changed from given timestamp are returned. New timestamp obtained from output will be used as an argument of the next call of this command.
 func (c *Client) GetTickPrices(level TickPriceInputLevel, symbols []string, t time.Time) ([]TickRecord, error) {
+	return c.GetTickPricesContext(context.Background(), level, symbols, t)
+}
+
+// GetTickPricesContext is like GetTickPrices, but stops waiting for the response when ctx is done.
+func (c *Client) GetTickPricesContext(ctx context.Context, level TickPriceInputLevel, symbols []string, t time.Time) ([]TickRecord, error) {
 	type getTickPricesInput struct {
 		Level     TickPriceInputLevel `json:"level"`
 		Symbols   []string            `json:"symbols"`
@@ -670,7 +741,7 @@ func (c *Client) GetTickPrices(level TickPriceInputLevel, symbols []string, t ti
 		Quotations []internal.TickRecord `json:"quotations"`
 	}
 
-	tickRecords, err := getSync[getTickPricesInput, getTickPricesResponse](c, "getTickPrices", getTickPricesInput{
+	tickRecords, err := getSync[getTickPricesInput, getTickPricesResponse](ctx, c, "getTickPrices", getTickPricesInput{
 		Level:     level,
 		Symbols:   symbols,
 		Timestamp: t.UnixMilli(),
@@ -729,13 +800,58 @@ type Trade struct {
 	Timestamp        time.Time
 }
 
+func newTrade(t internal.Trade) Trade {
+	var closeTime, expiration time.Time
+	if t.CloseTime != nil {
+		closeTime = time.UnixMilli(*t.CloseTime)
+	}
+
+	if t.Expiration != nil {
+		expiration = time.UnixMilli(*t.Expiration)
+	}
+
+	return Trade{
+		ClosePrice:       t.ClosePrice,
+		CloseTimeString:  t.CloseTimeString,
+		Closed:           t.Closed,
+		Cmd:              t.Cmd,
+		Comment:          t.Comment,
+		Commission:       t.Commission,
+		CustomComment:    t.CustomComment,
+		Digits:           t.Digits,
+		ExpirationString: t.ExpirationString,
+		MarginRate:       t.MarginRate,
+		Offset:           t.Offset,
+		OpenPrice:        t.OpenPrice,
+		OpenTimeString:   t.OpenTimeString,
+		OrderID:          t.OrderID,
+		Order2ID:         t.Order2ID,
+		Position:         t.Position,
+		Profit:           t.Profit,
+		Storage:          t.Storage,
+		Symbol:           t.Symbol,
+		StopLoss:         t.StopLoss,
+		TakeProfit:       t.TakeProfit,
+		Volume:           t.Volume,
+		OpenTime:         time.UnixMilli(t.OpenTime),
+		CloseTime:        closeTime,
+		Expiration:       expiration,
+		Timestamp:        time.UnixMilli(t.Timestamp),
+	}
+}
+
 // GetTradeRecords returns array of trades for given order IDs.
 func (c *Client) GetTradeRecords(orderIDs []int) ([]Trade, error) {
+	return c.GetTradeRecordsContext(context.Background(), orderIDs)
+}
+
+// GetTradeRecordsContext is like GetTradeRecords, but stops waiting for the response when ctx is done.
+func (c *Client) GetTradeRecordsContext(ctx context.Context, orderIDs []int) ([]Trade, error) {
 	type getTradeRecordsInput struct {
 		OrderIDs []int `json:"orders"`
 	}
 
-	trades, err := getSync[getTradeRecordsInput, []internal.Trade](c, "getTradeRecords", getTradeRecordsInput{
+	trades, err := getSync[getTradeRecordsInput, []internal.Trade](ctx, c, "getTradeRecords", getTradeRecordsInput{
 		OrderIDs: orderIDs,
 	})
 
@@ -745,43 +861,7 @@ func (c *Client) GetTradeRecords(orderIDs []int) ([]Trade, error) {
 
 	var res []Trade
 	for _, t := range trades {
-		var closeTime time.Time
-		var expiration time.Time
-		if t.CloseTime != nil {
-			closeTime = time.UnixMilli(*t.CloseTime)
-		}
-		if t.Expiration != nil {
-			expiration = time.UnixMilli(*t.Expiration)
-		}
-
-		res = append(res, Trade{
-			ClosePrice:       t.ClosePrice,
-			CloseTimeString:  t.CloseTimeString,
-			Closed:           t.Closed,
-			Cmd:              t.Cmd,
-			Comment:          t.Comment,
-			Commission:       t.Commission,
-			CustomComment:    t.CustomComment,
-			Digits:           t.Digits,
-			ExpirationString: t.ExpirationString,
-			MarginRate:       t.MarginRate,
-			Offset:           t.Offset,
-			OpenPrice:        t.OpenPrice,
-			OpenTimeString:   t.OpenTimeString,
-			OrderID:          t.OrderID,
-			Order2ID:         t.Order2ID,
-			Position:         t.Position,
-			Profit:           t.Profit,
-			Storage:          t.Storage,
-			Symbol:           t.Symbol,
-			StopLoss:         t.StopLoss,
-			TakeProfit:       t.TakeProfit,
-			Volume:           t.Volume,
-			OpenTime:         time.UnixMilli(t.OpenTime),
-			CloseTime:        closeTime,
-			Expiration:       expiration,
-			Timestamp:        time.UnixMilli(t.Timestamp),
-		})
+		res = append(res, newTrade(t))
 	}
 
 	return res, nil
@@ -796,9 +876,15 @@ var (
 	TradeStatusRejected TradeStatus = 4
 )
 
+// IsFinal reports whether the transaction is no longer processed by the server.
+func (s TradeStatus) IsFinal() bool {
+	return s == TradeStatusError || s == TradeStatusAccepted || s == TradeStatusRejected
+}
+
 type TradeTransactionStatus struct {
 	Ask           float64
 	Bid           float64
+	Price         float64 // Only set by the trade status stream
 	CustomComment string
 	Message       *string
 	OrderID       int
@@ -807,11 +893,16 @@ type TradeTransactionStatus struct {
 
 // GetTradeTransactionStatus returns current transaction status. At any time of transaction processing client might check the status of transaction on server side. In order to do that client must provide unique order ID taken from tradeTransaction invocation.
 func (c *Client) GetTradeTransactionStatus(orderID int) (TradeTransactionStatus, error) {
+	return c.GetTradeTransactionStatusContext(context.Background(), orderID)
+}
+
+// GetTradeTransactionStatusContext is like GetTradeTransactionStatus, but stops waiting for the response when ctx is done.
+func (c *Client) GetTradeTransactionStatusContext(ctx context.Context, orderID int) (TradeTransactionStatus, error) {
 	type tradeTransactionStatusInput struct {
 		OrderID int `json:"order"`
 	}
 
-	res, err := getSync[tradeTransactionStatusInput, internal.TradeTransactionStatus](c, "tradeTransactionStatus", tradeTransactionStatusInput{
+	res, err := getSync[tradeTransactionStatusInput, internal.TradeTransactionStatus](ctx, c, "tradeTransactionStatus", tradeTransactionStatusInput{
 		OrderID: orderID,
 	})
 	if err != nil {
@@ -860,6 +951,11 @@ How to verify that the trade request was accepted?
 The status field set to 'true' does not imply that the transaction was accepted. It only means, that the server acquired your request and began to process it. To analyse the status of the transaction (for example to verify if it was accepted or rejected) use the tradeTransactionStatus command with the order number, that came back with the response of the tradeTransaction command. You can find the example here: developers.xstore.pro/api/tutorials/opening_and_closing_trades2
 */
 func (c *Client) CreateTradeTransaction(input TradeTransactionInput) (orderID int, err error) {
+	return c.CreateTradeTransactionContext(context.Background(), input)
+}
+
+// CreateTradeTransactionContext is like CreateTradeTransaction, but stops waiting for the response when ctx is done.
+func (c *Client) CreateTradeTransactionContext(ctx context.Context, input TradeTransactionInput) (orderID int, err error) {
 	type tradeTransactionInput struct {
 		TradeTransactionInfo internal.TradeTransactionInfo `json:"tradeTransInfo"`
 	}
@@ -868,7 +964,7 @@ func (c *Client) CreateTradeTransaction(input TradeTransactionInput) (orderID in
 		OrderID int `json:"order"`
 	}
 
-	res, err := getSync[tradeTransactionInput, tradeTransactionResponse](c, "tradeTransaction", tradeTransactionInput{
+	res, err := getSync[tradeTransactionInput, tradeTransactionResponse](ctx, c, "tradeTransaction", tradeTransactionInput{
 		TradeTransactionInfo: internal.TradeTransactionInfo{
 			Command:       int(input.Command),
 			CustomComment: input.CustomComment,
@@ -893,12 +989,17 @@ func (c *Client) CreateTradeTransaction(input TradeTransactionInput) (orderID in
 
 // GetTradesHistory returns array of user's trades which were closed within specified period of time.
 func (c *Client) GetTradesHistory(start, end time.Time) ([]Trade, error) {
+	return c.GetTradesHistoryContext(context.Background(), start, end)
+}
+
+// GetTradesHistoryContext is like GetTradesHistory, but stops waiting for the response when ctx is done.
+func (c *Client) GetTradesHistoryContext(ctx context.Context, start, end time.Time) ([]Trade, error) {
 	type getTradesHistoryInput struct {
 		Start int64 `json:"start"`
 		End   int64 `json:"end"`
 	}
 
-	res, err := getSync[getTradesHistoryInput, []internal.Trade](c, "getTradesHistory", getTradesHistoryInput{
+	res, err := getSync[getTradesHistoryInput, []internal.Trade](ctx, c, "getTradesHistory", getTradesHistoryInput{
 		Start: start.UnixMilli(),
 		End:   end.UnixMilli(),
 	})
@@ -909,43 +1010,7 @@ func (c *Client) GetTradesHistory(start, end time.Time) ([]Trade, error) {
 
 	var trades []Trade
 	for _, trade := range res {
-		var closeTime, expiration time.Time
-		if trade.CloseTime != nil {
-			closeTime = time.UnixMilli(*trade.CloseTime)
-		}
-
-		if trade.Expiration != nil {
-			expiration = time.UnixMilli(*trade.Expiration)
-		}
-
-		trades = append(trades, Trade{
-			ClosePrice:       trade.ClosePrice,
-			CloseTimeString:  trade.CloseTimeString,
-			Closed:           trade.Closed,
-			Cmd:              trade.Cmd,
-			Comment:          trade.Comment,
-			Commission:       trade.Commission,
-			CustomComment:    trade.CustomComment,
-			Digits:           trade.Digits,
-			ExpirationString: trade.ExpirationString,
-			MarginRate:       trade.MarginRate,
-			Offset:           trade.Offset,
-			OpenPrice:        trade.OpenPrice,
-			OpenTimeString:   trade.OpenTimeString,
-			OrderID:          trade.OrderID,
-			Order2ID:         trade.Order2ID,
-			Position:         trade.Position,
-			Profit:           trade.Profit,
-			Storage:          trade.Storage,
-			Symbol:           trade.Symbol,
-			StopLoss:         trade.StopLoss,
-			TakeProfit:       trade.TakeProfit,
-			Volume:           trade.Volume,
-			OpenTime:         time.UnixMilli(trade.OpenTime),
-			CloseTime:        closeTime,
-			Expiration:       expiration,
-			Timestamp:        time.UnixMilli(trade.Timestamp),
-		})
+		trades = append(trades, newTrade(trade))
 	}
 
 	return trades, nil
@@ -953,11 +1018,16 @@ func (c *Client) GetTradesHistory(start, end time.Time) ([]Trade, error) {
 
 // GetTrades returns array of user's trades.
 func (c *Client) GetTrades(openedOnly bool) ([]Trade, error) {
+	return c.GetTradesContext(context.Background(), openedOnly)
+}
+
+// GetTradesContext is like GetTrades, but stops waiting for the response when ctx is done.
+func (c *Client) GetTradesContext(ctx context.Context, openedOnly bool) ([]Trade, error) {
 	type getTradesInput struct {
 		OpenedOnly bool `json:"openedOnly"`
 	}
 
-	res, err := getSync[getTradesInput, []internal.Trade](c, "getTrades", getTradesInput{
+	res, err := getSync[getTradesInput, []internal.Trade](ctx, c, "getTrades", getTradesInput{
 		OpenedOnly: openedOnly,
 	})
 
@@ -967,43 +1037,7 @@ func (c *Client) GetTrades(openedOnly bool) ([]Trade, error) {
 
 	var trades []Trade
 	for _, trade := range res {
-		var closeTime, expiration time.Time
-		if trade.CloseTime != nil {
-			closeTime = time.UnixMilli(*trade.CloseTime)
-		}
-
-		if trade.Expiration != nil {
-			expiration = time.UnixMilli(*trade.Expiration)
-		}
-
-		trades = append(trades, Trade{
-			ClosePrice:       trade.ClosePrice,
-			CloseTimeString:  trade.CloseTimeString,
-			Closed:           trade.Closed,
-			Cmd:              trade.Cmd,
-			Comment:          trade.Comment,
-			Commission:       trade.Commission,
-			CustomComment:    trade.CustomComment,
-			Digits:           trade.Digits,
-			ExpirationString: trade.ExpirationString,
-			MarginRate:       trade.MarginRate,
-			Offset:           trade.Offset,
-			OpenPrice:        trade.OpenPrice,
-			OpenTimeString:   trade.OpenTimeString,
-			OrderID:          trade.OrderID,
-			Order2ID:         trade.Order2ID,
-			Position:         trade.Position,
-			Profit:           trade.Profit,
-			Storage:          trade.Storage,
-			Symbol:           trade.Symbol,
-			StopLoss:         trade.StopLoss,
-			TakeProfit:       trade.TakeProfit,
-			Volume:           trade.Volume,
-			OpenTime:         time.UnixMilli(trade.OpenTime),
-			CloseTime:        closeTime,
-			Expiration:       expiration,
-			Timestamp:        time.UnixMilli(trade.Timestamp),
-		})
+		trades = append(trades, newTrade(trade))
 	}
 	return trades, nil
 }
@@ -1032,11 +1066,16 @@ type TradingHours map[string]map[DayOfWeek]struct {
 
 // GetTradingHours returns trading hours for given symbols.
 func (c *Client) GetTradingHours(symbols []string) (TradingHours, error) {
+	return c.GetTradingHoursContext(context.Background(), symbols)
+}
+
+// GetTradingHoursContext is like GetTradingHours, but stops waiting for the response when ctx is done.
+func (c *Client) GetTradingHoursContext(ctx context.Context, symbols []string) (TradingHours, error) {
 	type getTradingHoursInput struct {
 		Symbols []string `json:"symbols"`
 	}
 
-	res, err := getSync[getTradingHoursInput, []internal.TradingHours](c, "getTradingHours", getTradingHoursInput{
+	res, err := getSync[getTradingHoursInput, []internal.TradingHours](ctx, c, "getTradingHours", getTradingHoursInput{
 		Symbols: symbols,
 	})
 	if err != nil {
@@ -1091,11 +1130,16 @@ func (c *Client) GetTradingHours(symbols []string) (TradingHours, error) {
 
 // GetVersion returns the current API version.
 func (c *Client) GetVersion() (string, error) {
+	return c.GetVersionContext(context.Background())
+}
+
+// GetVersionContext is like GetVersion, but stops waiting for the response when ctx is done.
+func (c *Client) GetVersionContext(ctx context.Context) (string, error) {
 	type getVersionResponse struct {
 		Version string `json:"version"`
 	}
 
-	versionResponse, err := getSync[any, getVersionResponse](c, "getVersion", nil)
+	versionResponse, err := getSync[any, getVersionResponse](ctx, c, "getVersion", nil)
 	if err != nil {
 		return "", err
 	}
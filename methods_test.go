@@ -137,8 +137,6 @@ func initApiServer(ctx context.Context, readyCallback func()) {
 		panic(err)
 	}
 
-	defer client.Close()
-
 	err = client.Login()
 	if err != nil {
 		panic(err)
@@ -146,12 +144,8 @@ func initApiServer(ctx context.Context, readyCallback func()) {
 
 	readyCallback()
 
-	for {
-		select {
-		case <-ctx.Done():
-			break
-		}
-	}
+	// The client is shared by all tests running in parallel, so it stays open until the test binary exits.
+	select {}
 }
 
 func setupApi(ctx context.Context) {
@@ -0,0 +1,364 @@
+package xapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultOrderPollInterval = 5 * time.Second
+
+// Link is an entry of the link table of an OrderManager.
+type Link struct {
+	ID     string // Prefix of the client order ids of the linked orders
+	Orders []int  // Linked pending orders, the first one to be filled cancels the others
+	Filled int    // Position opened by the filled order, 0 while none was filled
+}
+
+// LinkStore persists the link table of an OrderManager.
+type LinkStore interface {
+	Load() ([]Link, error)
+	Save(links []Link) error
+}
+
+type fileLinkStore struct {
+	path string
+}
+
+// FileLinkStore stores the link table as JSON in the file at path. A missing file is an empty table.
+func FileLinkStore(path string) LinkStore {
+	return fileLinkStore{path: path}
+}
+
+func (s fileLinkStore) Load() ([]Link, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var links []Link
+	err = json.Unmarshal(data, &links)
+	return links, err
+}
+
+// Save writes to a temporary file first, so that the table is never left half written.
+func (s fileLinkStore) Save(links []Link) error {
+	data, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+type OrderManagerConfig struct {
+	Store        LinkStore     // Where the link table is persisted, nil to keep it in memory only
+	PollInterval time.Duration // How often the trades are checked, DefaultOrderPollInterval if zero
+	Stream       *StreamClient // Optional, trade updates received on it trigger a check right away
+}
+
+// OrderManager emulates one-cancels-other and bracket orders, which the API does not support. It places linked pending orders, watches the trades for one of them to be filled and then deletes the others. The link table is persisted, so that a manager created with the same store after a restart carries on.
+type OrderManager struct {
+	client *Client
+	config OrderManagerConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	cm    sync.Mutex // serializes checks
+	m     sync.Mutex // guards links
+	links []Link
+}
+
+// NewOrderManager loads the link table from config.Store and starts watching the trades of c until ctx is done or Close is called.
+func NewOrderManager(ctx context.Context, c *Client, config OrderManagerConfig) (*OrderManager, error) {
+	if config.PollInterval == 0 {
+		config.PollInterval = DefaultOrderPollInterval
+	}
+
+	var links []Link
+	if config.Store != nil {
+		var err error
+		links, err = config.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m := &OrderManager{
+		client: c,
+		config: config,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		links:  links,
+	}
+
+	var trades <-chan StreamTrade
+	if config.Stream != nil {
+		var err error
+		trades, err = config.Stream.SubscribeTrades(ctx)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+	}
+
+	go m.watch(ctx, trades)
+	return m, nil
+}
+
+// Close stops watching the trades. The linked orders are left as they are.
+func (m *OrderManager) Close() {
+	m.cancel()
+	<-m.done
+}
+
+// Links returns a copy of the link table.
+func (m *OrderManager) Links() []Link {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return slices.Clone(m.links)
+}
+
+func (m *OrderManager) watch(ctx context.Context, trades <-chan StreamTrade) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case _, ok := <-trades:
+			if !ok {
+				trades = nil
+				continue
+			}
+		}
+
+		// Errors are retried on the next check.
+		m.Check(ctx)
+	}
+}
+
+// PlaceOCO places the pending orders and links them, so that the first one to be filled cancels the others.
+func (m *OrderManager) PlaceOCO(ctx context.Context, orders ...TradeTransactionInput) (Link, error) {
+	return m.place(ctx, NewClientOrderID(), orders)
+}
+
+// PlaceBracket places the pending entry order with stopLoss and takeProfit as its exits. The server sets them on the position as soon as the entry is filled, so the position is protected also while no manager is running. A zero price sets no exit on that side.
+func (m *OrderManager) PlaceBracket(ctx context.Context, entry TradeTransactionInput, stopLoss, takeProfit float64) (Link, error) {
+	if stopLoss == 0 && takeProfit == 0 {
+		return Link{}, errors.New("a bracket needs a stop loss or a take profit")
+	}
+
+	entry.StopLoss = stopLoss
+	entry.TakeProfit = takeProfit
+	return m.place(ctx, NewClientOrderID(), []TradeTransactionInput{entry})
+}
+
+func checkPendingInput(input TradeTransactionInput) error {
+	if input.Type != OrderTypeOpen || input.Command < BuyLimitCommand || input.Command > SellStopCommand {
+		return fmt.Errorf("%s order with command %d is not a pending order", input.Symbol, input.Command)
+	}
+
+	return nil
+}
+
+// place places the orders and adds their link to the table. If an order cannot be placed, the ones placed before are deleted.
+func (m *OrderManager) place(ctx context.Context, id string, orders []TradeTransactionInput) (Link, error) {
+	if len(orders) == 0 {
+		return Link{}, errors.New("no orders to place")
+	}
+
+	for _, order := range orders {
+		err := checkPendingInput(order)
+		if err != nil {
+			return Link{}, err
+		}
+	}
+
+	placed, err := m.placeOrders(ctx, id, orders)
+	if err != nil {
+		for _, trade := range placed {
+			_, cerr := m.client.CancelPendingContext(ctx, trade)
+			err = errors.Join(err, cerr)
+		}
+		return Link{}, err
+	}
+
+	link := Link{ID: id}
+	for _, trade := range placed {
+		link.Orders = append(link.Orders, trade.OrderID)
+	}
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	m.links = append(m.links, link)
+	return link, m.save()
+}
+
+// placeOrders places the orders with ExecuteTradeOnce, with the client order ids id-0, id-1 and so on, so that placing them again after a failure never places an order twice. It stops at the first error and returns the orders placed before.
+func (m *OrderManager) placeOrders(ctx context.Context, id string, orders []TradeTransactionInput) ([]Trade, error) {
+	var placed []Trade
+	for i, order := range orders {
+		clientOrderID := fmt.Sprintf("%s-%d", id, i)
+		result, err := m.client.ExecuteTradeOnce(ctx, clientOrderID, order)
+		if errors.Is(err, ErrTradeRecordUnavailable) {
+			// The order was placed, so its record is looked up by the client order id instead.
+			result.Trade, err = m.client.FindTrade(ctx, clientOrderID)
+		}
+		if err == nil && result.Trade == nil {
+			err = fmt.Errorf("order %d was accepted, but no trade record was found", result.OrderID)
+		}
+		if err != nil {
+			return placed, err
+		}
+
+		placed = append(placed, *result.Trade)
+	}
+
+	return placed, nil
+}
+
+// save persists the link table. It must be called with m.m locked.
+func (m *OrderManager) save() error {
+	if m.config.Store == nil {
+		return nil
+	}
+
+	return m.config.Store.Save(m.links)
+}
+
+// Check looks for filled orders in the open trades once, and deletes their siblings. Orders which are no longer open and have not opened a position found in the trades history, e.g. because they were deleted by hand, are unlinked. Check is called periodically by the manager, calling it directly is only needed to react right away.
+func (m *OrderManager) Check(ctx context.Context) error {
+	m.cm.Lock()
+	defer m.cm.Unlock()
+
+	// The links are copied before the trades are fetched, so that a link added meanwhile by place is left for the next check instead of being checked against trades which do not list its orders yet.
+	links := m.Links()
+	trades, err := m.client.GetTradesContext(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	open := make(map[int]Trade, len(trades))
+	for _, t := range trades {
+		open[t.OrderID] = t
+	}
+
+	var errs []error
+	for _, link := range links {
+		updated, err := m.checkLink(ctx, link, trades, open)
+		errs = append(errs, err)
+
+		m.m.Lock()
+		i := slices.IndexFunc(m.links, func(l Link) bool { return l.ID == link.ID })
+		switch {
+		case i < 0:
+		case updated == nil:
+			m.links = slices.Delete(m.links, i, i+1)
+		default:
+			m.links[i] = *updated
+		}
+		errs = append(errs, m.save())
+		m.m.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkLink returns link as it should be stored after handling its filled order, or nil if it is done. An order is filled once an open or closed position carries its client order id.
+func (m *OrderManager) checkLink(ctx context.Context, link Link, trades []Trade, open map[int]Trade) (*Link, error) {
+	if link.Filled == 0 {
+		link.Filled = filledPosition(link, trades)
+	}
+
+	if link.Filled == 0 && slices.ContainsFunc(link.Orders, func(id int) bool { _, ok := open[id]; return !ok }) {
+		// An order which is no longer open may have been filled and closed again since the last check.
+		history, err := m.client.recentTradesHistory(ctx)
+		if err != nil {
+			return &link, err
+		}
+		link.Filled = filledPosition(link, history)
+	}
+
+	var pending []Trade
+	for _, id := range link.Orders {
+		t, ok := open[id]
+		if ok && id != link.Filled && !isPosition(t) {
+			pending = append(pending, t)
+		}
+	}
+
+	if link.Filled == 0 {
+		link.Orders = nil
+		for _, t := range pending {
+			link.Orders = append(link.Orders, t.OrderID)
+		}
+
+		if len(link.Orders) == 0 {
+			return nil, nil
+		}
+		return &link, nil
+	}
+
+	// The link is kept until all siblings are deleted, so that the ones which could not be deleted are tried again on the next check.
+	var errs []error
+	for _, t := range pending {
+		_, err := m.client.CancelPendingContext(ctx, t)
+		errs = append(errs, err)
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return &link, err
+	}
+	return nil, nil
+}
+
+// filledPosition returns the position among trades opened by one of the orders of link, or 0 if there is none.
+func filledPosition(link Link, trades []Trade) int {
+	for _, t := range trades {
+		if isPosition(t) && strings.HasPrefix(t.CustomComment, link.ID+"-") {
+			return t.OrderID
+		}
+	}
+
+	return 0
+}
+
+func isPosition(t Trade) bool {
+	command := TradeCommand(t.Cmd)
+	return command == BuyCommand || command == SellCommand
+}
@@ -0,0 +1,351 @@
+package xapi_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+	"github.com/voxelost/xapi/xapitest"
+)
+
+// orderBook is a server keeping the open positions and pending orders placed on it. Every transaction is accepted right away, except for the first failDeletes deletions of pending orders.
+type orderBook struct {
+	*xapitest.Server
+
+	m           sync.Mutex
+	orders      map[int]map[string]any // trade records by order number
+	history     []map[string]any       // trade records of closed positions
+	next        int
+	failDeletes int
+}
+
+func newOrderBook(t *testing.T) *orderBook {
+	ob := &orderBook{
+		Server: xapitest.NewServer(),
+		orders: make(map[int]map[string]any),
+		next:   100,
+	}
+	t.Cleanup(ob.Close)
+
+	ob.Handle("tradeTransaction", func(req xapitest.Request) (any, error) {
+		var args struct {
+			TradeTransInfo tradeInfo `json:"tradeTransInfo"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		ob.m.Lock()
+		defer ob.m.Unlock()
+
+		info := args.TradeTransInfo
+		switch xapi.OrderType(info.Type) {
+		case xapi.OrderTypeOpen:
+			ob.next++
+			ob.orders[ob.next] = map[string]any{"order": ob.next, "cmd": info.Cmd, "volume": info.Volume, "open_price": info.Price, "symbol": info.Symbol, "customComment": info.CustomComment, "sl": info.StopLoss, "tp": info.TakeProfit}
+		case xapi.OrderTypeDelete:
+			if ob.failDeletes > 0 {
+				ob.failDeletes--
+				return nil, xapi.ErrInternal
+			}
+			delete(ob.orders, info.Order)
+		}
+		return map[string]any{"order": ob.next}, nil
+	})
+	ob.Handle("tradeTransactionStatus", func(req xapitest.Request) (any, error) {
+		return map[string]any{"requestStatus": xapi.TradeStatusAccepted}, nil
+	})
+	ob.Handle("getTradeRecords", func(req xapitest.Request) (any, error) {
+		var args struct {
+			Orders []int `json:"orders"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		ob.m.Lock()
+		defer ob.m.Unlock()
+
+		var records []map[string]any
+		for _, order := range args.Orders {
+			if record, ok := ob.orders[order]; ok {
+				records = append(records, record)
+			}
+		}
+		return records, nil
+	})
+	ob.Handle("getTrades", func(xapitest.Request) (any, error) {
+		ob.m.Lock()
+		defer ob.m.Unlock()
+
+		var records []map[string]any
+		for _, record := range ob.orders {
+			records = append(records, record)
+		}
+		return records, nil
+	})
+	ob.Handle("getTradesHistory", func(xapitest.Request) (any, error) {
+		ob.m.Lock()
+		defer ob.m.Unlock()
+		return append([]map[string]any{}, ob.history...), nil
+	})
+	return ob
+}
+
+// fill turns the pending order into an open position.
+func (ob *orderBook) fill(t *testing.T, order int) {
+	ob.m.Lock()
+	defer ob.m.Unlock()
+
+	record, ok := ob.orders[order]
+	if !ok {
+		t.Fatalf("order %d does not exist", order)
+	}
+	record["cmd"] = record["cmd"].(int) % 2
+}
+
+// closePosition moves the position to the trades history.
+func (ob *orderBook) closePosition(t *testing.T, order int) {
+	ob.m.Lock()
+	defer ob.m.Unlock()
+
+	record, ok := ob.orders[order]
+	if !ok {
+		t.Fatalf("order %d does not exist", order)
+	}
+	delete(ob.orders, order)
+	ob.history = append(ob.history, record)
+}
+
+// quote closes the long positions whose stop loss or take profit is reached at price and moves them to the trades history.
+func (ob *orderBook) quote(price float64) {
+	ob.m.Lock()
+	defer ob.m.Unlock()
+
+	for order, record := range ob.orders {
+		sl, _ := record["sl"].(float64)
+		tp, _ := record["tp"].(float64)
+		if record["cmd"] == int(xapi.BuyCommand) && (sl != 0 && price <= sl || tp != 0 && price >= tp) {
+			delete(ob.orders, order)
+			ob.history = append(ob.history, record)
+		}
+	}
+}
+
+func (ob *orderBook) exists(order int) bool {
+	ob.m.Lock()
+	defer ob.m.Unlock()
+	_, ok := ob.orders[order]
+	return ok
+}
+
+func (ob *orderBook) login(t *testing.T) *xapi.Client {
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(ob.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func pendingOrder(command xapi.TradeCommand, price float64) xapi.TradeTransactionInput {
+	return xapi.TradeTransactionInput{
+		Command: command,
+		Price:   price,
+		Symbol:  "EURUSD",
+		Type:    xapi.OrderTypeOpen,
+		Volume:  1,
+	}
+}
+
+func TestOCO(t *testing.T) {
+	t.Parallel()
+	ob := newOrderBook(t)
+	c := ob.login(t)
+
+	m, err := xapi.NewOrderManager(context.Background(), c, xapi.OrderManagerConfig{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	link, err := m.PlaceOCO(context.Background(), pendingOrder(xapi.BuyStopCommand, 1.2), pendingOrder(xapi.SellStopCommand, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(link.Orders) != 2 {
+		t.Fatalf("expected 2 linked orders, got %+v", link)
+	}
+
+	err = m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ob.exists(link.Orders[0]) || !ob.exists(link.Orders[1]) {
+		t.Fatal("expected the orders to stay pending")
+	}
+
+	ob.fill(t, link.Orders[1])
+	err = m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ob.exists(link.Orders[0]) {
+		t.Error("expected the sibling to be deleted")
+	}
+	if !ob.exists(link.Orders[1]) {
+		t.Error("expected the position to stay open")
+	}
+	if links := m.Links(); len(links) != 0 {
+		t.Errorf("expected the link to be removed, got %+v", links)
+	}
+}
+
+func TestOCODeleteFails(t *testing.T) {
+	t.Parallel()
+	ob := newOrderBook(t)
+	c := ob.login(t)
+
+	m, err := xapi.NewOrderManager(context.Background(), c, xapi.OrderManagerConfig{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	link, err := m.PlaceOCO(context.Background(), pendingOrder(xapi.BuyStopCommand, 1.2), pendingOrder(xapi.SellStopCommand, 1), pendingOrder(xapi.BuyLimitCommand, 1.05))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ob.fill(t, link.Orders[1])
+	ob.m.Lock()
+	ob.failDeletes = 1
+	ob.m.Unlock()
+
+	err = m.Check(context.Background())
+	if !errors.Is(err, xapi.ErrInternal) {
+		t.Fatalf("expected the failed deletion to be reported, got %v", err)
+	}
+	if links := m.Links(); len(links) != 1 || links[0].Filled != link.Orders[1] {
+		t.Fatalf("expected the filled link to be kept, got %+v", links)
+	}
+
+	err = m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ob.exists(link.Orders[0]) || ob.exists(link.Orders[2]) {
+		t.Error("expected the siblings to be deleted")
+	}
+	if !ob.exists(link.Orders[1]) {
+		t.Error("expected the position to stay open")
+	}
+	if links := m.Links(); len(links) != 0 {
+		t.Errorf("expected the link to be removed, got %+v", links)
+	}
+}
+
+func TestOCOFilledAndClosedBetweenChecks(t *testing.T) {
+	t.Parallel()
+	ob := newOrderBook(t)
+	c := ob.login(t)
+
+	m, err := xapi.NewOrderManager(context.Background(), c, xapi.OrderManagerConfig{PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	link, err := m.PlaceOCO(context.Background(), pendingOrder(xapi.BuyStopCommand, 1.2), pendingOrder(xapi.SellStopCommand, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ob.fill(t, link.Orders[1])
+	ob.closePosition(t, link.Orders[1])
+	err = m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ob.exists(link.Orders[0]) {
+		t.Error("expected the sibling to be deleted")
+	}
+	if links := m.Links(); len(links) != 0 {
+		t.Errorf("expected the link to be removed, got %+v", links)
+	}
+}
+
+func TestBracketSurvivesRestart(t *testing.T) {
+	t.Parallel()
+	ob := newOrderBook(t)
+	c := ob.login(t)
+	store := xapi.FileLinkStore(filepath.Join(t.TempDir(), "links.json"))
+
+	m, err := xapi.NewOrderManager(context.Background(), c, xapi.OrderManagerConfig{Store: store, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := m.PlaceBracket(context.Background(), pendingOrder(xapi.BuyLimitCommand, 1.1), 1, 1.3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	ob.m.Lock()
+	sl, tp := ob.orders[entry.Orders[0]]["sl"], ob.orders[entry.Orders[0]]["tp"]
+	ob.m.Unlock()
+	if sl != 1.0 || tp != 1.3 {
+		t.Errorf("expected the exits to be placed with the entry, got %v and %v", sl, tp)
+	}
+
+	ob.quote(0.9)
+	if !ob.exists(entry.Orders[0]) {
+		t.Fatal("expected the entry to stay pending")
+	}
+
+	// The position is closed by its stop loss while no manager is running.
+	ob.fill(t, entry.Orders[0])
+	ob.quote(0.99)
+	if ob.exists(entry.Orders[0]) {
+		t.Fatal("expected the stop loss to close the position")
+	}
+
+	// A new manager picks up the bracket from the store.
+	m, err = xapi.NewOrderManager(context.Background(), c, xapi.OrderManagerConfig{Store: store, PollInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if links := m.Links(); len(links) != 1 {
+		t.Fatalf("expected the bracket to be loaded, got %+v", links)
+	}
+
+	err = m.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 0 {
+		t.Errorf("expected the link table to be empty, got %+v", links)
+	}
+}
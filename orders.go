@@ -0,0 +1,221 @@
+package xapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Side int
+
+var (
+	SideBuy  Side = 0
+	SideSell Side = 1
+)
+
+// OrderOptions are the optional parameters of a new order.
+type OrderOptions struct {
+	StopLoss      float64   // 0 for none
+	TakeProfit    float64   // 0 for none
+	Offset        int       // Trailing offset, 0 for none
+	Expiration    time.Time // Expiration of pending orders, zero for none
+	CustomComment string
+}
+
+// Buy opens a long position at the current ask price and waits for the final status of the transaction.
+func (c *Client) Buy(symbol string, volume float64, opts OrderOptions) (TradeResult, error) {
+	return c.BuyContext(context.Background(), symbol, volume, opts)
+}
+
+// BuyContext is like Buy, but stops waiting when ctx is done.
+func (c *Client) BuyContext(ctx context.Context, symbol string, volume float64, opts OrderOptions) (TradeResult, error) {
+	return c.openMarket(ctx, SideBuy, symbol, volume, opts)
+}
+
+// Sell opens a short position at the current bid price and waits for the final status of the transaction.
+func (c *Client) Sell(symbol string, volume float64, opts OrderOptions) (TradeResult, error) {
+	return c.SellContext(context.Background(), symbol, volume, opts)
+}
+
+// SellContext is like Sell, but stops waiting when ctx is done.
+func (c *Client) SellContext(ctx context.Context, symbol string, volume float64, opts OrderOptions) (TradeResult, error) {
+	return c.openMarket(ctx, SideSell, symbol, volume, opts)
+}
+
+// PlaceLimit places a pending order filled once the price reaches price or better, and waits for the final status of the transaction.
+func (c *Client) PlaceLimit(side Side, symbol string, volume, price float64, opts OrderOptions) (TradeResult, error) {
+	return c.PlaceLimitContext(context.Background(), side, symbol, volume, price, opts)
+}
+
+// PlaceLimitContext is like PlaceLimit, but stops waiting when ctx is done.
+func (c *Client) PlaceLimitContext(ctx context.Context, side Side, symbol string, volume, price float64, opts OrderOptions) (TradeResult, error) {
+	command := BuyLimitCommand
+	if side == SideSell {
+		command = SellLimitCommand
+	}
+
+	return c.ExecuteTrade(ctx, newOrderInput(command, symbol, volume, price, opts))
+}
+
+// PlaceStop places a pending order filled once the price moves past price, and waits for the final status of the transaction.
+func (c *Client) PlaceStop(side Side, symbol string, volume, price float64, opts OrderOptions) (TradeResult, error) {
+	return c.PlaceStopContext(context.Background(), side, symbol, volume, price, opts)
+}
+
+// PlaceStopContext is like PlaceStop, but stops waiting when ctx is done.
+func (c *Client) PlaceStopContext(ctx context.Context, side Side, symbol string, volume, price float64, opts OrderOptions) (TradeResult, error) {
+	command := BuyStopCommand
+	if side == SideSell {
+		command = SellStopCommand
+	}
+
+	return c.ExecuteTrade(ctx, newOrderInput(command, symbol, volume, price, opts))
+}
+
+// ClosePosition closes volume of the open position trade at the current price, or the whole position if volume is 0, and waits for the final status of the transaction.
+func (c *Client) ClosePosition(trade Trade, volume float64) (TradeResult, error) {
+	return c.ClosePositionContext(context.Background(), trade, volume)
+}
+
+// ClosePositionContext is like ClosePosition, but stops waiting when ctx is done.
+func (c *Client) ClosePositionContext(ctx context.Context, trade Trade, volume float64) (TradeResult, error) {
+	err := checkOpen(trade, false)
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	if volume == 0 {
+		volume = trade.Volume
+	}
+
+	// A long position is closed by selling at the bid price and a short one by buying at the ask price.
+	closeSide := SideSell
+	if TradeCommand(trade.Cmd) == SellCommand {
+		closeSide = SideBuy
+	}
+
+	price, err := c.marketPrice(ctx, *trade.Symbol, closeSide)
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	return c.ExecuteTrade(ctx, TradeTransactionInput{
+		Command: TradeCommand(trade.Cmd),
+		Order:   trade.OrderID,
+		Price:   price,
+		Symbol:  *trade.Symbol,
+		Type:    OrderTypeClose,
+		Volume:  volume,
+	})
+}
+
+// ModifyStops sets the stop loss and take profit of an open position or a pending order, 0 removes them. It waits for the final status of the transaction.
+func (c *Client) ModifyStops(trade Trade, stopLoss, takeProfit float64) (TradeResult, error) {
+	return c.ModifyStopsContext(context.Background(), trade, stopLoss, takeProfit)
+}
+
+// ModifyStopsContext is like ModifyStops, but stops waiting when ctx is done.
+func (c *Client) ModifyStopsContext(ctx context.Context, trade Trade, stopLoss, takeProfit float64) (TradeResult, error) {
+	err := checkOpen(trade, isPending(TradeCommand(trade.Cmd)))
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	return c.ExecuteTrade(ctx, TradeTransactionInput{
+		Command:    TradeCommand(trade.Cmd),
+		Expiration: trade.Expiration,
+		Offset:     trade.Offset,
+		Order:      trade.OrderID,
+		Price:      trade.OpenPrice,
+		StopLoss:   stopLoss,
+		Symbol:     *trade.Symbol,
+		TakeProfit: takeProfit,
+		Type:       OrderTypeModify,
+		Volume:     trade.Volume,
+	})
+}
+
+// CancelPending deletes the pending order and waits for the final status of the transaction.
+func (c *Client) CancelPending(order Trade) (TradeResult, error) {
+	return c.CancelPendingContext(context.Background(), order)
+}
+
+// CancelPendingContext is like CancelPending, but stops waiting when ctx is done.
+func (c *Client) CancelPendingContext(ctx context.Context, order Trade) (TradeResult, error) {
+	err := checkOpen(order, true)
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	return c.ExecuteTrade(ctx, TradeTransactionInput{
+		Command: TradeCommand(order.Cmd),
+		Order:   order.OrderID,
+		Price:   order.OpenPrice,
+		Symbol:  *order.Symbol,
+		Type:    OrderTypeDelete,
+		Volume:  order.Volume,
+	})
+}
+
+func newOrderInput(command TradeCommand, symbol string, volume, price float64, opts OrderOptions) TradeTransactionInput {
+	return TradeTransactionInput{
+		Command:       command,
+		CustomComment: opts.CustomComment,
+		Expiration:    opts.Expiration,
+		Offset:        opts.Offset,
+		Price:         price,
+		StopLoss:      opts.StopLoss,
+		Symbol:        symbol,
+		TakeProfit:    opts.TakeProfit,
+		Type:          OrderTypeOpen,
+		Volume:        volume,
+	}
+}
+
+// checkOpen returns an error unless trade is an open position, or a pending order if pending is set.
+func checkOpen(trade Trade, pending bool) error {
+	if trade.Closed {
+		return fmt.Errorf("order %d is closed", trade.OrderID)
+	}
+
+	if trade.Symbol == nil {
+		return fmt.Errorf("order %d has no symbol", trade.OrderID)
+	}
+
+	command := TradeCommand(trade.Cmd)
+	if pending && (command < BuyLimitCommand || command > SellStopCommand) {
+		return fmt.Errorf("order %d is not a pending order", trade.OrderID)
+	}
+	if !pending && command != BuyCommand && command != SellCommand {
+		return fmt.Errorf("order %d is not a position", trade.OrderID)
+	}
+
+	return nil
+}
+
+func (c *Client) openMarket(ctx context.Context, side Side, symbol string, volume float64, opts OrderOptions) (TradeResult, error) {
+	price, err := c.marketPrice(ctx, symbol, side)
+	if err != nil {
+		return TradeResult{}, err
+	}
+
+	command := BuyCommand
+	if side == SideSell {
+		command = SellCommand
+	}
+
+	return c.ExecuteTrade(ctx, newOrderInput(command, symbol, volume, price, opts))
+}
+
+// marketPrice returns the current price of symbol for a trade on side, the ask price when buying and the bid price when selling.
+func (c *Client) marketPrice(ctx context.Context, symbol string, side Side) (float64, error) {
+	s, err := c.GetSymbolContext(ctx, symbol)
+	if err != nil {
+		return 0, err
+	}
+
+	if side == SideSell {
+		return s.Bid, nil
+	}
+	return s.Ask, nil
+}
@@ -0,0 +1,233 @@
+package xapi_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+	"github.com/voxelost/xapi/xapitest"
+)
+
+type tradeInfo struct {
+	Cmd           int     `json:"cmd"`
+	CustomComment string  `json:"customComment"`
+	Order         int     `json:"order"`
+	Price         float64 `json:"price"`
+	StopLoss      float64 `json:"sl"`
+	Symbol        string  `json:"symbol"`
+	TakeProfit    float64 `json:"tp"`
+	Type          int     `json:"type"`
+	Volume        float64 `json:"volume"`
+}
+
+// tradingServer accepts every transaction after reporting it as pending once, unless reject is set. Accepted transactions have a trade record with order2 set to their order number. The connection is dropped instead of answering the first lostBefore transactions, and after recording the next lostAfter ones. The first hidden getTrades calls leave out the recorded transactions, as if they were still being processed.
+type tradingServer struct {
+	*xapitest.Server
+
+	m          sync.Mutex
+	trades     []tradeInfo
+	polls      map[int]int
+	reject     bool
+	lostBefore int
+	lostAfter  int
+	hidden     int
+}
+
+func newTradingServer(t *testing.T) (*tradingServer, *xapi.Client) {
+	ts := &tradingServer{
+		Server: xapitest.NewServer(),
+		polls:  make(map[int]int),
+	}
+	t.Cleanup(ts.Close)
+
+	ts.Respond("getSymbol", map[string]any{"symbol": "EURUSD", "ask": 1.1002, "bid": 1.1})
+	ts.Handle("tradeTransaction", func(req xapitest.Request) (any, error) {
+		var args struct {
+			TradeTransInfo tradeInfo `json:"tradeTransInfo"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		ts.m.Lock()
+		defer ts.m.Unlock()
+		if ts.lostBefore > 0 {
+			ts.lostBefore--
+			return nil, xapitest.ErrDisconnect
+		}
+
+		ts.trades = append(ts.trades, args.TradeTransInfo)
+		if ts.lostAfter > 0 {
+			ts.lostAfter--
+			return nil, xapitest.ErrDisconnect
+		}
+		return map[string]any{"order": 100 + len(ts.trades)}, nil
+	})
+	ts.Handle("getTrades", func(xapitest.Request) (any, error) {
+		ts.m.Lock()
+		defer ts.m.Unlock()
+
+		if ts.hidden > 0 {
+			ts.hidden--
+			return []any{}, nil
+		}
+
+		var records []map[string]any
+		for i, trade := range ts.trades {
+			records = append(records, map[string]any{"order": 1101 + i, "order2": 101 + i, "open_price": trade.Price, "customComment": trade.CustomComment})
+		}
+		return records, nil
+	})
+	ts.Respond("getTradesHistory", []any{})
+	ts.Handle("tradeTransactionStatus", func(req xapitest.Request) (any, error) {
+		var args struct {
+			Order int `json:"order"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		ts.m.Lock()
+		defer ts.m.Unlock()
+		ts.polls[args.Order]++
+
+		status := xapi.TradeStatusAccepted
+		switch {
+		case ts.polls[args.Order] == 1:
+			status = xapi.TradeStatusPending
+		case ts.reject:
+			status = xapi.TradeStatusRejected
+		}
+		return map[string]any{"order": args.Order, "requestStatus": status, "message": "market closed"}, nil
+	})
+
+	ts.Handle("getTradeRecords", func(req xapitest.Request) (any, error) {
+		var args struct {
+			Orders []int `json:"orders"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		ts.m.Lock()
+		defer ts.m.Unlock()
+
+		var records []map[string]any
+		for _, order := range args.Orders {
+			var comment string
+			if i := order - 101; i >= 0 && i < len(ts.trades) {
+				comment = ts.trades[i].CustomComment
+			}
+			records = append(records, map[string]any{"order": order + 1000, "order2": order, "open_price": 1.1001, "symbol": "EURUSD", "customComment": comment})
+		}
+		return records, nil
+	})
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(ts.URL), xapi.WithReconnect(xapi.ReconnectPolicy{
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+	}), xapi.WithIdempotency(xapi.IdempotencyPolicy{
+		GracePeriod: 500 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ts, c
+}
+
+func (ts *tradingServer) lastTrade(t *testing.T) tradeInfo {
+	t.Helper()
+	ts.m.Lock()
+	defer ts.m.Unlock()
+
+	if len(ts.trades) == 0 {
+		t.Fatal("expected a transaction")
+	}
+	return ts.trades[len(ts.trades)-1]
+}
+
+func TestOrderHelpers(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+
+	symbol := "EURUSD"
+	position := xapi.Trade{OrderID: 7, Cmd: int(xapi.SellCommand), Symbol: &symbol, Volume: 2, OpenPrice: 1.2}
+	pending := xapi.Trade{OrderID: 8, Cmd: int(xapi.BuyLimitCommand), Symbol: &symbol, Volume: 1, OpenPrice: 1.05}
+
+	tests := []struct {
+		name   string
+		run    func() (xapi.TradeResult, error)
+		expect tradeInfo
+	}{
+		{"buy", func() (xapi.TradeResult, error) {
+			return c.Buy(symbol, 0.1, xapi.OrderOptions{StopLoss: 1.09})
+		}, tradeInfo{Cmd: 0, Price: 1.1002, StopLoss: 1.09, Symbol: symbol, Type: 0, Volume: 0.1}},
+		{"sell", func() (xapi.TradeResult, error) {
+			return c.Sell(symbol, 0.1, xapi.OrderOptions{TakeProfit: 1.09})
+		}, tradeInfo{Cmd: 1, Price: 1.1, TakeProfit: 1.09, Symbol: symbol, Type: 0, Volume: 0.1}},
+		{"sell limit", func() (xapi.TradeResult, error) {
+			return c.PlaceLimit(xapi.SideSell, symbol, 1, 1.2, xapi.OrderOptions{})
+		}, tradeInfo{Cmd: 3, Price: 1.2, Symbol: symbol, Type: 0, Volume: 1}},
+		{"buy stop", func() (xapi.TradeResult, error) {
+			return c.PlaceStop(xapi.SideBuy, symbol, 1, 1.2, xapi.OrderOptions{})
+		}, tradeInfo{Cmd: 4, Price: 1.2, Symbol: symbol, Type: 0, Volume: 1}},
+		{"close", func() (xapi.TradeResult, error) {
+			return c.ClosePosition(position, 0)
+		}, tradeInfo{Cmd: 1, Order: 7, Price: 1.1002, Symbol: symbol, Type: 2, Volume: 2}},
+		{"modify", func() (xapi.TradeResult, error) {
+			return c.ModifyStops(position, 1.3, 1)
+		}, tradeInfo{Cmd: 1, Order: 7, Price: 1.2, StopLoss: 1.3, TakeProfit: 1, Symbol: symbol, Type: 3, Volume: 2}},
+		{"cancel", func() (xapi.TradeResult, error) {
+			return c.CancelPending(pending)
+		}, tradeInfo{Cmd: 2, Order: 8, Price: 1.05, Symbol: symbol, Type: 4, Volume: 1}},
+	}
+
+	for _, test := range tests {
+		result, err := test.run()
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if result.Status != xapi.TradeStatusAccepted {
+			t.Errorf("%s: expected the accepted status, got %+v", test.name, result)
+		}
+		if trade := ts.lastTrade(t); trade != test.expect {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expect, trade)
+		}
+	}
+
+	_, err := c.CancelPending(position)
+	if err == nil {
+		t.Error("expected a position not to be cancelled as a pending order")
+	}
+}
+
+func TestOrderRejected(t *testing.T) {
+	t.Parallel()
+	ts, c := newTradingServer(t)
+	ts.m.Lock()
+	ts.reject = true
+	ts.m.Unlock()
+
+	result, err := c.Buy("EURUSD", 1, xapi.OrderOptions{})
+	if !errors.Is(err, xapi.ErrTradeRejected) {
+		t.Fatalf("expected ErrTradeRejected, got %v", err)
+	}
+	if result.Status != xapi.TradeStatusRejected || result.Message != "market closed" || result.Trade != nil {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
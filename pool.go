@@ -0,0 +1,204 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrNoHealthyClient = errors.New("no healthy client in pool")
+
+type PoolConfig struct {
+	Size                int           // Number of connections, at most MaxSize
+	MaxSize             int           // Number of sessions the pool may open at once, DefaultMaxPoolSize if zero
+	HealthCheckInterval time.Duration // How often every connection is pinged, DefaultHealthCheckInterval if zero
+	HealthCheckTimeout  time.Duration // How long a ping may take, DefaultHealthCheckTimeout if zero
+	DrainTimeout        time.Duration // How long calls in flight on a client being shut down are waited for, DefaultDrainTimeout if zero
+}
+
+const (
+	// DefaultMaxPoolSize keeps pools below the limit of concurrent sessions per account enforced by the server.
+	DefaultMaxPoolSize         = 10
+	DefaultHealthCheckInterval = time.Minute
+	DefaultHealthCheckTimeout  = 10 * time.Second
+	DefaultDrainTimeout        = 30 * time.Second
+)
+
+// Pool keeps several logged in clients of the same account and spreads calls over them. Clients which stop answering ping are replaced.
+type Pool struct {
+	config   PoolConfig
+	opts     []optFunc
+	ctx      context.Context // context of the clients
+	checkCtx context.Context // context of the health check, cancelled by Close
+	cancel   context.CancelFunc
+	done     chan struct{} // closed once the health check stopped
+
+	m       sync.Mutex
+	clients []*Client // nil for a slot whose client could not be replaced yet
+	next    int
+
+	drains sync.WaitGroup // clients being shut down
+}
+
+// NewPool opens config.Size clients created with opts and logs them in.
+func NewPool(ctx context.Context, config PoolConfig, opts ...optFunc) (*Pool, error) {
+	if config.MaxSize == 0 {
+		config.MaxSize = DefaultMaxPoolSize
+	}
+
+	if config.Size < 1 || config.Size > config.MaxSize {
+		return nil, errors.New("invalid pool size")
+	}
+
+	if config.HealthCheckInterval == 0 {
+		config.HealthCheckInterval = DefaultHealthCheckInterval
+	}
+
+	if config.HealthCheckTimeout == 0 {
+		config.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+
+	if config.DrainTimeout == 0 {
+		config.DrainTimeout = DefaultDrainTimeout
+	}
+
+	checkCtx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		config:   config,
+		opts:     opts,
+		ctx:      ctx,
+		checkCtx: checkCtx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		clients:  make([]*Client, config.Size),
+	}
+
+	for i := range p.clients {
+		c, err := p.newClient()
+		if err != nil {
+			close(p.done)
+			p.Close()
+			return nil, err
+		}
+		p.clients[i] = c
+	}
+
+	go p.healthCheck()
+	return p, nil
+}
+
+func (p *Pool) newClient() (*Client, error) {
+	c, err := NewClient(p.ctx, p.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.LoginContext(p.checkCtx)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Client returns the next healthy client in round robin order.
+func (p *Pool) Client() (*Client, error) {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	for range p.clients {
+		c := p.clients[p.next]
+		p.next = (p.next + 1) % len(p.clients)
+		if c != nil {
+			return c, nil
+		}
+	}
+
+	return nil, ErrNoHealthyClient
+}
+
+// Close shuts every client of the pool down with Shutdown, so that their sessions are logged out. Calls in flight are waited for up to DrainTimeout.
+func (p *Pool) Close() {
+	p.cancel()
+	<-p.done
+
+	p.m.Lock()
+	for i, c := range p.clients {
+		if c != nil {
+			p.shutdown(c)
+			p.clients[i] = nil
+		}
+	}
+	p.m.Unlock()
+
+	p.drains.Wait()
+}
+
+// shutdown shuts c down in the background. c may still be used by callers which got it from Client before.
+func (p *Pool) shutdown(c *Client) {
+	p.drains.Add(1)
+	go func() {
+		defer p.drains.Done()
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.config.DrainTimeout)
+		defer cancel()
+		c.Shutdown(ctx)
+	}()
+}
+
+func (p *Pool) healthCheck() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.checkCtx.Done():
+			return
+		case <-ticker.C:
+			for i := range p.config.Size {
+				p.check(i)
+			}
+		}
+	}
+}
+
+// check pings the client in slot i and replaces it if it does not answer. The replaced client is shut down once the calls in flight on it are done.
+func (p *Pool) check(i int) {
+	p.m.Lock()
+	c := p.clients[i]
+	p.m.Unlock()
+
+	if c != nil {
+		ctx, cancel := context.WithTimeout(p.checkCtx, p.config.HealthCheckTimeout)
+		err := c.ping(ctx)
+		cancel()
+		if err == nil || p.checkCtx.Err() != nil {
+			return
+		}
+	}
+
+	p.m.Lock()
+	if c != nil && p.clients[i] == c {
+		p.clients[i] = nil
+		p.shutdown(c)
+	}
+	p.m.Unlock()
+
+	c, err := p.newClient()
+	if err != nil {
+		return
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.checkCtx.Err() != nil {
+		p.shutdown(c)
+		return
+	}
+	p.clients[i] = c
+}
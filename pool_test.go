@@ -0,0 +1,107 @@
+package xapi_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t, "ping")
+
+	p, err := xapi.NewPool(context.Background(), xapi.PoolConfig{
+		Size:                2,
+		HealthCheckInterval: 10 * time.Millisecond,
+		HealthCheckTimeout:  time.Second,
+	}, xapi.WithURL(fs.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := p.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := p.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("expected calls to be spread over both clients")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fs.loginCount() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the client which failed ping to be replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for range 2 {
+		c, err := p.Client()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = c.GetVersion()
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	p.Close()
+	if fs.logoutCount() < 2 {
+		t.Errorf("expected the sessions to be logged out, got %d logouts", fs.logoutCount())
+	}
+}
+
+func TestPoolDrainsReplacedClient(t *testing.T) {
+	t.Parallel()
+	fs := newFlakyServer(t)
+	fs.delay["getServerTime"] = 200 * time.Millisecond
+
+	p, err := xapi.NewPool(context.Background(), xapi.PoolConfig{
+		Size:                1,
+		HealthCheckInterval: 20 * time.Millisecond,
+		HealthCheckTimeout:  10 * time.Millisecond,
+	}, xapi.WithURL(fs.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c, err := p.Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The ping is queued behind the slow call and times out, so the client is replaced while the call is in flight.
+	_, err = c.GetServerTime()
+	if err != nil {
+		t.Fatalf("expected the call in flight to finish, got %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fs.loginCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the client to be replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolSize(t *testing.T) {
+	_, err := xapi.NewPool(context.Background(), xapi.PoolConfig{Size: xapi.DefaultMaxPoolSize + 1})
+	if err == nil {
+		t.Error("expected pools above DefaultMaxPoolSize to be refused")
+	}
+
+	_, err = xapi.NewPool(context.Background(), xapi.PoolConfig{Size: 3, MaxSize: 2})
+	if err == nil {
+		t.Error("expected pools above MaxSize to be refused")
+	}
+}
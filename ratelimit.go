@@ -0,0 +1,93 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type RateLimitStats struct {
+	Requests  int           // Commands that passed the limiter
+	Delayed   int           // Commands that had to wait
+	TotalWait time.Duration // Sum of the time commands waited
+	MaxWait   time.Duration // Longest time a single command waited
+}
+
+// rateLimiter lets a burst of commands through at once, then one command per interval.
+type rateLimiter struct {
+	interval time.Duration
+	burst    int
+
+	m     sync.Mutex
+	next  time.Time // theoretical time of the next command if there was no burst
+	stats RateLimitStats
+}
+
+// WithRateLimit spaces the commands sent on the connection, including login and the background ping, so that after a burst of burst commands at most one is sent every interval. The server drops connections which send commands too often, e.g. WithRateLimit(200*time.Millisecond, 1) keeps to the documented 200 ms spacing.
+func WithRateLimit(interval time.Duration, burst int) optFunc {
+	return func(c *Client) error {
+		if interval <= 0 || burst < 1 {
+			return errors.New("invalid rate limit")
+		}
+
+		c.limiter = &rateLimiter{
+			interval: interval,
+			burst:    burst,
+		}
+		return nil
+	}
+}
+
+// wait blocks until the next command may be sent. A slot reserved by a command abandoned because of ctx is not given back.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.m.Lock()
+	now := time.Now()
+	next := l.next
+	if next.Before(now) {
+		next = now
+	}
+
+	delay := next.Add(-time.Duration(l.burst-1) * l.interval).Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	l.next = next.Add(l.interval)
+
+	l.stats.Requests++
+	if delay > 0 {
+		l.stats.Delayed++
+		l.stats.TotalWait += delay
+		l.stats.MaxWait = max(l.stats.MaxWait, delay)
+	}
+	l.m.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RateLimitStats returns how long commands waited for the limiter set with WithRateLimit.
+func (c *Client) RateLimitStats() RateLimitStats {
+	if c.limiter == nil {
+		return RateLimitStats{}
+	}
+
+	c.limiter.m.Lock()
+	defer c.limiter.m.Unlock()
+	return c.limiter.stats
+}
@@ -0,0 +1,121 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ReconnectPolicy describes how a Client recovers from a dropped connection. The connection is redialed up to MaxAttempts times, waiting MinBackoff before the first attempt and doubling the wait up to MaxBackoff after every failure. If the client was logged in, it logs in again with the stored credentials.
+type ReconnectPolicy struct {
+	MaxAttempts int // Zero means unlimited
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	MaxAttempts: 5,
+	MinBackoff:  500 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+}
+
+// WithReconnect enables reconnecting after the connection drops. Calls failing because of the dropped connection are retried once the client is connected again, except for tradeTransaction which is never sent twice.
+func WithReconnect(policy ReconnectPolicy) optFunc {
+	return func(c *Client) error {
+		if policy.MaxAttempts < 0 || policy.MinBackoff < 0 || policy.MaxBackoff < policy.MinBackoff {
+			return errors.New("invalid reconnect policy")
+		}
+
+		c.reconnectPolicy = &policy
+		return nil
+	}
+}
+
+var nonIdempotentCommands = map[string]bool{
+	"tradeTransaction": true,
+}
+
+func isIdempotent(command string) bool {
+	return !nonIdempotentCommands[command]
+}
+
+// shouldReconnect reports whether err means the connection is unusable and the policy allows to recover it.
+func (c *Client) shouldReconnect(err error) bool {
+	if c.reconnectPolicy == nil {
+		return false
+	}
+
+	var apiErr ApiError
+	if errors.As(err, &apiErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// reconnect replaces the broken connection with a new one and logs in again if needed. If another call already replaced it, reconnect returns right away.
+func (c *Client) reconnect(ctx context.Context, broken *connection) error {
+	err := c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if c.connection() != broken {
+		return nil
+	}
+	broken.close()
+	c.setState(StateConnecting, nil)
+
+	backoff := c.reconnectPolicy.MinBackoff
+	for attempt := 0; c.reconnectPolicy.MaxAttempts == 0 || attempt < c.reconnectPolicy.MaxAttempts; attempt++ {
+		select {
+		case <-c.done:
+			return ErrClientClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, c.reconnectPolicy.MaxBackoff)
+
+		var conn *connection
+		conn, err = c.dial(ctx)
+		if err != nil {
+			continue
+		}
+
+		if c.loggedIn {
+			err = login(ctx, c, conn)
+		}
+
+		if err == nil {
+			err = c.setConnection(conn)
+			if err != nil {
+				return err
+			}
+
+			state := StateConnected
+			if c.loggedIn {
+				state = StateLoggedIn
+			}
+			c.setState(state, nil)
+			return nil
+		}
+
+		conn.close()
+
+		var apiErr ApiError
+		if errors.As(err, &apiErr) {
+			break
+		}
+	}
+
+	c.setState(StateDegraded, err)
+	return err
+}
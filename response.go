@@ -18,3 +18,22 @@ type errorResponse struct {
 	ErrorCode        *string `json:"errorCode,omitempty"`
 	ErrorDescription *string `json:"errorDescr,omitempty"`
 }
+
+func (r response[T]) err() error {
+	if r.Status {
+		return nil
+	}
+
+	var errorCode, errorDescription string
+	if r.ErrorCode != nil {
+		errorCode = *r.ErrorCode
+	}
+	if r.ErrorDescription != nil {
+		errorDescription = *r.ErrorDescription
+	}
+
+	return ApiError{
+		Code:    errorCode,
+		Message: errorDescription,
+	}
+}
@@ -0,0 +1,130 @@
+package xapi
+
+import (
+	"context"
+	"errors"
+)
+
+// begin registers a call in flight. It fails once Shutdown was called.
+func (c *Client) begin() error {
+	c.am.Lock()
+	defer c.am.Unlock()
+
+	if c.idle != nil {
+		return ErrClientClosed
+	}
+
+	c.active++
+	return nil
+}
+
+func (c *Client) end() {
+	c.am.Lock()
+	defer c.am.Unlock()
+
+	c.active--
+	if c.idle != nil && c.active == 0 {
+		close(c.idle)
+	}
+}
+
+func (c *Client) trackStream(s *StreamClient) error {
+	c.am.Lock()
+	defer c.am.Unlock()
+
+	if c.idle != nil {
+		return ErrClientClosed
+	}
+
+	c.streams[s] = struct{}{}
+	go func() {
+		<-s.Done()
+
+		c.am.Lock()
+		defer c.am.Unlock()
+		delete(c.streams, s)
+	}()
+
+	return nil
+}
+
+// Logout ends the session on the server. The connection stays open and Login may be called again.
+func (c *Client) Logout() error {
+	return c.LogoutContext(context.Background())
+}
+
+// LogoutContext is like Logout, but stops waiting for the response when ctx is done.
+func (c *Client) LogoutContext(ctx context.Context) error {
+	err := c.begin()
+	if err != nil {
+		return err
+	}
+	defer c.end()
+
+	return logout(ctx, c)
+}
+
+func logout(ctx context.Context, c *Client) error {
+	err := c.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.unlock()
+
+	if !c.loggedIn {
+		return nil
+	}
+
+	_, err = send[any](ctx, c.connection(), "logout", nil)
+	if err != nil {
+		return err
+	}
+
+	c.loggedIn = false
+	c.streamSessionID = ""
+	c.setState(StateConnected, nil)
+	return nil
+}
+
+// Shutdown stops the client gracefully: new calls fail with ErrClientClosed, the calls in flight are waited for, the session is logged out, the streams opened with Stream are closed and finally the connection is closed. If ctx is done before the calls in flight finish, the client is closed without waiting any longer. Shutdown returns every error met on the way.
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.am.Lock()
+	if c.idle != nil {
+		c.am.Unlock()
+		return ErrClientClosed
+	}
+
+	c.idle = make(chan struct{})
+	if c.active == 0 {
+		close(c.idle)
+	}
+	c.am.Unlock()
+
+	var errs []error
+	select {
+	case <-c.idle:
+	case <-ctx.Done():
+		errs = append(errs, ctx.Err())
+	}
+
+	if ctx.Err() == nil {
+		err := logout(ctx, c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c.am.Lock()
+	streams := make([]*StreamClient, 0, len(c.streams))
+	for s := range c.streams {
+		streams = append(streams, s)
+	}
+	c.am.Unlock()
+
+	for _, s := range streams {
+		s.Close()
+	}
+
+	c.Close()
+	return errors.Join(errs...)
+}
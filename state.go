@@ -0,0 +1,89 @@
+package xapi
+
+type ConnectionState int
+
+const (
+	StateConnecting ConnectionState = iota + 1 // the connection is being dialed or redialed
+	StateConnected                             // connected, but not logged in
+	StateLoggedIn                              // connected and logged in
+	StateDegraded                              // the connection failed or did not answer ping
+	StateClosed                                // closed by Close or Shutdown
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateLoggedIn:
+		return "logged in"
+	case StateDegraded:
+		return "degraded"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+type StateEvent struct {
+	State ConnectionState
+	Err   error // What caused the state, e.g. the failed ping, nil if not applicable
+}
+
+// WithStateHandler sets a function called on every change of the connection state, and on every further failure while the client is degraded. It is called synchronously, so it should return quickly.
+func WithStateHandler(handler func(StateEvent)) optFunc {
+	return func(c *Client) error {
+		c.stateHandler = handler
+		return nil
+	}
+}
+
+// State returns the current connection state.
+func (c *Client) State() ConnectionState {
+	c.stm.Lock()
+	defer c.stm.Unlock()
+	return c.state
+}
+
+func (c *Client) setState(state ConnectionState, err error) {
+	c.stm.Lock()
+	if c.state == StateClosed || (c.state == state && err == nil) {
+		c.stm.Unlock()
+		return
+	}
+
+	c.state = state
+	if state == StateConnected || state == StateLoggedIn {
+		c.healthyState = state
+	}
+	handler := c.stateHandler
+	c.stm.Unlock()
+
+	if handler != nil {
+		handler(StateEvent{
+			State: state,
+			Err:   err,
+		})
+	}
+}
+
+// recoverState leaves StateDegraded after the connection answered again.
+func (c *Client) recoverState() {
+	c.stm.Lock()
+	degraded := c.state == StateDegraded
+	healthy := c.healthyState
+	c.stm.Unlock()
+
+	if degraded {
+		c.setState(healthy, nil)
+	}
+}
+
+// connectionFailed is called by the reader of conn once it stopped. Failures of connections being replaced by reconnect are not reported.
+func (c *Client) connectionFailed(conn *connection, err error) {
+	if c.connection() == conn && c.State() != StateConnecting {
+		c.setState(StateDegraded, err)
+	}
+}
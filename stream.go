@@ -0,0 +1,719 @@
+package xapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/voxelost/xapi/internal"
+)
+
+var (
+	ErrStreamClosed = errors.New("stream closed")
+	ErrStreamStale  = errors.New("stream stale: no keep alive received")
+)
+
+const (
+	DefaultStreamPingInterval = time.Minute
+	DefaultKeepAliveTimeout   = 30 * time.Second
+)
+
+type streamCommand struct {
+	Command         string `json:"command"`
+	StreamSessionID string `json:"streamSessionId,omitempty"`
+}
+
+type streamMessage struct {
+	Command string          `json:"command"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// subscriberBuffer is how many messages a subscriber may fall behind before further messages are dropped for it.
+const subscriberBuffer = 64
+
+type subscriber struct {
+	command string
+	stop    any
+	key     string
+	ch      chan json.RawMessage
+}
+
+// StreamClient is a connection to the streaming socket. Instead of answering commands it pushes messages to the subscribers registered with its Subscribe* methods. Messages are dropped for a subscriber whose channel is not read while it falls too far behind, so that one slow consumer does not hold up the others; Dropped counts them.
+type StreamClient struct {
+	conn      *websocket.Conn
+	sessionID string
+	cancel    context.CancelFunc
+	done      chan struct{}
+	err       error
+
+	pingInterval     time.Duration
+	keepAliveTimeout time.Duration
+
+	subscribers map[string]map[*subscriber]struct{}
+	refs        map[string]int
+	dropped     int
+
+	wm sync.Mutex // guards writes to conn
+	sm sync.Mutex // guards subscribers, refs, dropped and err
+}
+
+type streamOptFunc func(*StreamClient)
+
+// WithStreamPingInterval sets how often ping is sent on the stream socket. Zero disables pinging.
+func WithStreamPingInterval(d time.Duration) streamOptFunc {
+	return func(s *StreamClient) {
+		s.pingInterval = d
+	}
+}
+
+// WithKeepAliveTimeout sets how long the stream may go without a keep alive message before it is closed with ErrStreamStale. Zero disables the watchdog.
+func WithKeepAliveTimeout(d time.Duration) streamOptFunc {
+	return func(s *StreamClient) {
+		s.keepAliveTimeout = d
+	}
+}
+
+// Stream opens the streaming connection for the session created by Login. The stream is closed when ctx is done or Close is called, or with ErrStreamStale when the server stops sending keep alive messages.
+func (c *Client) Stream(ctx context.Context, opts ...streamOptFunc) (*StreamClient, error) {
+	err := c.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := c.streamSessionID
+	streamURL := c.streamURL
+	if streamURL == nil && c.url != nil {
+		u := *c.url
+		u.Path = strings.TrimSuffix(u.Path, "/") + "Stream"
+		streamURL = &u
+	}
+	c.unlock()
+
+	if sessionID == "" {
+		return nil, errors.New("stream session id is missing, login first")
+	}
+
+	if streamURL == nil {
+		return nil, errors.New("stream url is required")
+	}
+
+	s, err := newStreamClient(ctx, c.dialer, c.header, streamURL, sessionID, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.trackStream(s)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func newStreamClient(ctx context.Context, dialer *websocket.Dialer, header http.Header, u *url.URL, sessionID string, opts ...streamOptFunc) (*StreamClient, error) {
+	conn, err := dialWebsocket(ctx, dialer, u, header)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &StreamClient{
+		conn:             conn,
+		sessionID:        sessionID,
+		cancel:           cancel,
+		done:             make(chan struct{}),
+		pingInterval:     DefaultStreamPingInterval,
+		keepAliveTimeout: DefaultKeepAliveTimeout,
+		subscribers:      make(map[string]map[*subscriber]struct{}),
+		refs:             make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	go s.readLoop()
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+
+	if s.pingInterval > 0 {
+		go s.pingLoop(ctx)
+	}
+
+	if s.keepAliveTimeout > 0 {
+		err = s.watchKeepAlive(ctx)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Close closes the stream connection. All subscription channels are closed afterwards.
+func (s *StreamClient) Close() {
+	s.fail(ErrStreamClosed)
+}
+
+func (s *StreamClient) fail(err error) {
+	s.sm.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.sm.Unlock()
+
+	s.cancel()
+	s.conn.Close()
+}
+
+// Done returns a channel that is closed once the stream connection is gone.
+func (s *StreamClient) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the stream stopped, or nil while it is still running.
+func (s *StreamClient) Err() error {
+	select {
+	case <-s.done:
+	default:
+		return nil
+	}
+
+	s.sm.Lock()
+	defer s.sm.Unlock()
+	return s.err
+}
+
+func (s *StreamClient) readLoop() {
+	defer close(s.done)
+
+	for {
+		var msg streamMessage
+		err := s.conn.ReadJSON(&msg)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+
+		s.dispatch(msg)
+	}
+}
+
+func (s *StreamClient) dispatch(msg streamMessage) {
+	s.sm.Lock()
+	subs := make([]*subscriber, 0, len(s.subscribers[msg.Command]))
+	for sub := range s.subscribers[msg.Command] {
+		subs = append(subs, sub)
+	}
+	s.sm.Unlock()
+
+	// A subscriber which falls behind loses messages instead of stalling the other subscriptions and the keep alive watchdog.
+	dropped := 0
+	for _, sub := range subs {
+		select {
+		case sub.ch <- msg.Data:
+		default:
+			dropped++
+		}
+	}
+
+	if dropped > 0 {
+		s.sm.Lock()
+		s.dropped += dropped
+		s.sm.Unlock()
+	}
+}
+
+// Dropped returns how many messages were dropped so far for subscribers which fell behind.
+func (s *StreamClient) Dropped() int {
+	s.sm.Lock()
+	defer s.sm.Unlock()
+	return s.dropped
+}
+
+func (s *StreamClient) pingLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.writeJSON(s.newCommand("ping"))
+			if err != nil {
+				s.fail(err)
+				return
+			}
+		}
+	}
+}
+
+// watchKeepAlive subscribes to keep alive messages and fails the stream with ErrStreamStale when none arrives within keepAliveTimeout.
+func (s *StreamClient) watchKeepAlive(ctx context.Context) error {
+	sub, err := s.subscribe("keepAlive", s.newCommand("getKeepAlive"), streamCommand{Command: "stopKeepAlive"})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer s.unsubscribe(sub)
+
+		timer := time.NewTimer(s.keepAliveTimeout)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-sub.ch:
+				timer.Reset(s.keepAliveTimeout)
+			case <-timer.C:
+				s.fail(ErrStreamStale)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (s *StreamClient) writeJSON(cmd any) error {
+	s.wm.Lock()
+	defer s.wm.Unlock()
+	return s.conn.WriteJSON(cmd)
+}
+
+func (s *StreamClient) newCommand(command string) streamCommand {
+	return streamCommand{
+		Command:         command,
+		StreamSessionID: s.sessionID,
+	}
+}
+
+// subscribe registers a subscriber for messages of the given command and sends start. stop is sent by unsubscribe once no other subscriber with an equal stop command is left.
+func (s *StreamClient) subscribe(command string, start, stop any) (*subscriber, error) {
+	key, err := json.Marshal(stop)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &subscriber{
+		command: command,
+		stop:    stop,
+		key:     string(key),
+		ch:      make(chan json.RawMessage, subscriberBuffer),
+	}
+
+	s.sm.Lock()
+	if s.subscribers[command] == nil {
+		s.subscribers[command] = make(map[*subscriber]struct{})
+	}
+	s.subscribers[command][sub] = struct{}{}
+	s.refs[sub.key]++
+	s.sm.Unlock()
+
+	if start != nil {
+		err = s.writeJSON(start)
+		if err != nil {
+			s.unsubscribe(sub)
+			return nil, err
+		}
+	}
+
+	return sub, nil
+}
+
+func (s *StreamClient) unsubscribe(sub *subscriber) error {
+	s.sm.Lock()
+	delete(s.subscribers[sub.command], sub)
+	s.refs[sub.key]--
+	last := s.refs[sub.key] == 0
+	if last {
+		delete(s.refs, sub.key)
+	}
+	s.sm.Unlock()
+
+	if !last || sub.stop == nil {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+
+	return s.writeJSON(sub.stop)
+}
+
+// subscribeStream sends start and returns a channel of the data of every message of the given command, decoded as R and converted by convert. Messages for which convert returns false are skipped. The channel is closed when ctx is done or the stream stops.
+func subscribeStream[R, T any](ctx context.Context, s *StreamClient, command string, start, stop any, convert func(R) (T, bool)) (<-chan T, error) {
+	sub, err := s.subscribe(command, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		defer s.unsubscribe(sub)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.done:
+				return
+			case data := <-sub.ch:
+				var r R
+				err := json.Unmarshal(data, &r)
+				if err != nil {
+					continue
+				}
+
+				v, ok := convert(r)
+				if !ok {
+					continue
+				}
+
+				select {
+				case out <- v:
+				case <-ctx.Done():
+					return
+				case <-s.done:
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// SubscribeTickPrices returns a channel of tick prices for the given symbol. minArrivalTime is the minimal interval between two consecutive updates; maxLevel is the maximum level of the quote the user is interested in. stopTickPrices is sent once ctx is done.
+func (s *StreamClient) SubscribeTickPrices(ctx context.Context, symbol string, minArrivalTime time.Duration, maxLevel int) (<-chan TickRecord, error) {
+	type getTickPricesInput struct {
+		streamCommand
+		Symbol         string `json:"symbol"`
+		MinArrivalTime int64  `json:"minArrivalTime"`
+		MaxLevel       int    `json:"maxLevel"`
+	}
+
+	type stopTickPricesInput struct {
+		streamCommand
+		Symbol string `json:"symbol"`
+	}
+
+	start := getTickPricesInput{
+		streamCommand:  s.newCommand("getTickPrices"),
+		Symbol:         symbol,
+		MinArrivalTime: minArrivalTime.Milliseconds(),
+		MaxLevel:       maxLevel,
+	}
+
+	stop := stopTickPricesInput{
+		streamCommand: streamCommand{Command: "stopTickPrices"},
+		Symbol:        symbol,
+	}
+
+	return subscribeStream(ctx, s, "tickPrices", start, stop, func(q internal.TickRecord) (TickRecord, bool) {
+		return TickRecord{
+			Ask:         q.Ask,
+			AskVolume:   q.AskVolume,
+			Bid:         q.Bid,
+			BidVolume:   q.BidVolume,
+			High:        q.High,
+			Level:       q.Level,
+			Low:         q.Low,
+			SpreadRaw:   q.SpreadRaw,
+			SpreadTable: q.SpreadTable,
+			Symbol:      q.Symbol,
+			Timestamp:   time.UnixMilli(q.Timestamp),
+		}, q.Symbol == symbol
+	})
+}
+
+// SubscribeCandles returns a channel of completed one minute candles for the given symbol. Unlike the candles returned by GetChartLast and GetChartRange, the prices are absolute values in base currency instead of shifts from the open price. stopCandles is sent once ctx is done.
+func (s *StreamClient) SubscribeCandles(ctx context.Context, symbol string) (<-chan ChartRangeRateInfo, error) {
+	type candlesInput struct {
+		streamCommand
+		Symbol string `json:"symbol"`
+	}
+
+	start := candlesInput{
+		streamCommand: s.newCommand("getCandles"),
+		Symbol:        symbol,
+	}
+
+	stop := candlesInput{
+		streamCommand: streamCommand{Command: "stopCandles"},
+		Symbol:        symbol,
+	}
+
+	return subscribeStream(ctx, s, "candle", start, stop, func(c internal.StreamCandle) (ChartRangeRateInfo, bool) {
+		return ChartRangeRateInfo{
+			Close:                 c.Close,
+			CandleStartTimeString: c.CandleStartTimeString,
+			High:                  c.High,
+			Low:                   c.Low,
+			Open:                  c.Open,
+			Volume:                c.Volume,
+			CandleStartTime:       time.UnixMilli(c.CandleStartTime),
+		}, c.Symbol == symbol
+	})
+}
+
+type TradeState string
+
+var (
+	TradeStateModified TradeState = "Modified"
+	TradeStateDeleted  TradeState = "Deleted"
+)
+
+type StreamTrade struct {
+	Trade
+	State TradeState // Modified, or Deleted when a pending order was cancelled
+	Type  OrderType  // OrderTypeOpen, OrderTypePending or OrderTypeClose
+}
+
+// SubscribeTrades returns a channel of trades which is fed every time a position or pending order is opened, modified or closed. When a new order is sent, the trade is streamed with the order number of the transaction; once the position opens it is streamed again with its position number. Trades are dropped while the channel is not read and falls too far behind, see Dropped. stopTrades is sent once ctx is done.
+func (s *StreamClient) SubscribeTrades(ctx context.Context) (<-chan StreamTrade, error) {
+	start := s.newCommand("getTrades")
+	stop := streamCommand{Command: "stopTrades"}
+
+	return subscribeStream(ctx, s, "trade", start, stop, func(t internal.StreamTrade) (StreamTrade, bool) {
+		return StreamTrade{
+			Trade: newTrade(t.Trade),
+			State: TradeState(t.State),
+			Type:  OrderType(t.Type),
+		}, true
+	})
+}
+
+func newStreamTradeStatus(ts internal.StreamTradeStatus) TradeTransactionStatus {
+	return TradeTransactionStatus{
+		Price:         ts.Price,
+		CustomComment: ts.CustomComment,
+		Message:       ts.Message,
+		OrderID:       ts.Order,
+		RequestStatus: ts.RequestStatus,
+	}
+}
+
+// SubscribeTradeStatus returns a channel of status updates of the transactions sent with CreateTradeTransaction, identified by their OrderID. Statuses are dropped while the channel is not read and falls too far behind, see Dropped. stopTradeStatus is sent once ctx is done.
+func (s *StreamClient) SubscribeTradeStatus(ctx context.Context) (<-chan TradeTransactionStatus, error) {
+	start := s.newCommand("getTradeStatus")
+	stop := streamCommand{Command: "stopTradeStatus"}
+
+	return subscribeStream(ctx, s, "tradeStatus", start, stop, func(ts internal.StreamTradeStatus) (TradeTransactionStatus, bool) {
+		return newStreamTradeStatus(ts), true
+	})
+}
+
+// maxUnclaimedTradeStatuses is how many final statuses a TradeStatusWatcher keeps for orders nobody awaited yet. The oldest ones are dropped beyond that.
+const maxUnclaimedTradeStatuses = 1024
+
+// TradeStatusWatcher collects the final statuses of transactions from the moment it is created, so that a status pushed right after CreateTradeTransaction returns is not missed.
+type TradeStatusWatcher struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    func() error
+
+	m        sync.Mutex // guards statuses, arrivals and changed
+	statuses map[int]TradeTransactionStatus
+	arrivals []int         // order IDs of statuses in the order they arrived
+	changed  chan struct{} // closed and replaced whenever a status arrives
+}
+
+// WatchTradeStatuses subscribes to trade statuses until ctx is done or Close is called. Create it before sending the transactions to await:
+//
+//	w, err := s.WatchTradeStatuses(ctx)
+//	...
+//	orderID, err := c.CreateTradeTransactionContext(ctx, input)
+//	...
+//	status, err := w.Await(ctx, orderID)
+func (s *StreamClient) WatchTradeStatuses(ctx context.Context) (*TradeStatusWatcher, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	statuses, err := s.SubscribeTradeStatus(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	w := &TradeStatusWatcher{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		err: func() error {
+			if ctx.Err() != nil {
+				return ErrStreamClosed
+			}
+			return s.Err()
+		},
+		statuses: make(map[int]TradeTransactionStatus),
+		changed:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+		for ts := range statuses {
+			if TradeStatus(ts.RequestStatus).IsFinal() {
+				w.record(ts)
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Close stops the subscription. Pending Await calls return ErrStreamClosed.
+func (w *TradeStatusWatcher) Close() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *TradeStatusWatcher) record(ts TradeTransactionStatus) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	if _, ok := w.statuses[ts.OrderID]; !ok {
+		w.arrivals = append(w.arrivals, ts.OrderID)
+	}
+	w.statuses[ts.OrderID] = ts
+
+	for len(w.arrivals) > maxUnclaimedTradeStatuses {
+		delete(w.statuses, w.arrivals[0])
+		w.arrivals = w.arrivals[1:]
+	}
+
+	close(w.changed)
+	w.changed = make(chan struct{})
+}
+
+// take removes the status of orderID, if it arrived, and returns it. Otherwise it returns a channel closed once another status arrives.
+func (w *TradeStatusWatcher) take(orderID int) (TradeTransactionStatus, bool, <-chan struct{}) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	ts, ok := w.statuses[orderID]
+	if !ok {
+		return TradeTransactionStatus{}, false, w.changed
+	}
+
+	delete(w.statuses, orderID)
+	w.arrivals = slices.DeleteFunc(w.arrivals, func(id int) bool { return id == orderID })
+	return ts, true, nil
+}
+
+// Await waits until the transaction with the given order ID is accepted, rejected or fails, and returns its final status. Statuses received since the watcher was created are taken into account, each of them is returned once.
+func (w *TradeStatusWatcher) Await(ctx context.Context, orderID int) (TradeTransactionStatus, error) {
+	for {
+		ts, ok, changed := w.take(orderID)
+		if ok {
+			return ts, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return TradeTransactionStatus{}, ctx.Err()
+		case <-w.done:
+			// A status recorded right before the subscription ended is still returned.
+			ts, ok, _ := w.take(orderID)
+			if ok {
+				return ts, nil
+			}
+			return TradeTransactionStatus{}, w.err()
+		case <-changed:
+		}
+	}
+}
+
+// AwaitTradeStatus is a shorthand for a TradeStatusWatcher created now. It only sees statuses pushed after it was called; to await a transaction which is yet to be sent, create the watcher with WatchTradeStatuses before sending it.
+func (s *StreamClient) AwaitTradeStatus(ctx context.Context, orderID int) (TradeTransactionStatus, error) {
+	w, err := s.WatchTradeStatuses(ctx)
+	if err != nil {
+		return TradeTransactionStatus{}, err
+	}
+	defer w.Close()
+
+	return w.Await(ctx, orderID)
+}
+
+// SubscribeBalance returns a channel of account indicators which is fed every time they change. The stream does not carry the account currency, so Currency is left empty. stopBalance is sent once ctx is done.
+func (s *StreamClient) SubscribeBalance(ctx context.Context) (<-chan MarginLevel, error) {
+	start := s.newCommand("getBalance")
+	stop := streamCommand{Command: "stopBalance"}
+
+	return subscribeStream(ctx, s, "balance", start, stop, func(b internal.StreamBalance) (MarginLevel, bool) {
+		return MarginLevel{
+			Balance:     b.Balance,
+			Credit:      b.Credit,
+			Equity:      b.Equity,
+			Margin:      b.Margin,
+			MarginFree:  b.MarginFree,
+			MarginLevel: b.MarginLevel,
+		}, true
+	})
+}
+
+type Profit struct {
+	OrderID  int
+	Order2ID int
+	Position int
+	Profit   float64 // Profit in account currency
+}
+
+// SubscribeProfits returns a channel of profit updates of the open positions. stopProfits is sent once ctx is done.
+func (s *StreamClient) SubscribeProfits(ctx context.Context) (<-chan Profit, error) {
+	start := s.newCommand("getProfits")
+	stop := streamCommand{Command: "stopProfits"}
+
+	return subscribeStream(ctx, s, "profit", start, stop, func(p internal.StreamProfit) (Profit, bool) {
+		return Profit{
+			OrderID:  p.Order,
+			Order2ID: p.Order2,
+			Position: p.Position,
+			Profit:   p.Profit,
+		}, true
+	})
+}
+
+// SubscribeNews returns a channel of news topics pushed as they are published. The stream does not carry TimeString. stopNews is sent once ctx is done.
+func (s *StreamClient) SubscribeNews(ctx context.Context) (<-chan NewsTopic, error) {
+	start := s.newCommand("getNews")
+	stop := streamCommand{Command: "stopNews"}
+
+	return subscribeStream(ctx, s, "news", start, stop, func(n internal.StreamNews) (NewsTopic, bool) {
+		return NewsTopic{
+			Body:       n.Body,
+			BodyLength: len(n.Body),
+			Key:        n.Key,
+			Title:      n.Title,
+			Time:       time.UnixMilli(n.Time),
+		}, true
+	})
+}
+
+// SubscribeKeepAlive returns a channel of the timestamps of the keep alive messages sent by the server every few seconds. stopKeepAlive is sent once ctx is done and no other keep alive subscription, including the watchdog set with WithKeepAliveTimeout, is left.
+func (s *StreamClient) SubscribeKeepAlive(ctx context.Context) (<-chan time.Time, error) {
+	start := s.newCommand("getKeepAlive")
+	stop := streamCommand{Command: "stopKeepAlive"}
+
+	return subscribeStream(ctx, s, "keepAlive", start, stop, func(k internal.StreamKeepAlive) (time.Time, bool) {
+		return time.UnixMilli(k.Timestamp), true
+	})
+}
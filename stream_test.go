@@ -0,0 +1,558 @@
+package xapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/voxelost/xapi"
+)
+
+const testStreamSessionID = "test-stream-session"
+
+type mockStream struct {
+	t      *testing.T
+	server *httptest.Server
+	url    string
+
+	m        sync.Mutex
+	conn     *websocket.Conn
+	commands chan map[string]any
+}
+
+// newMockStream starts a server that accepts any login on the sync socket and records commands received on the stream socket.
+func newMockStream(t *testing.T) *mockStream {
+	ms := &mockStream{
+		t:        t,
+		commands: make(chan map[string]any, 64),
+	}
+
+	ms.server = httptest.NewServer(http.HandlerFunc(ms.handle))
+	ms.url = "ws://" + strings.TrimPrefix(ms.server.URL, "http://")
+	t.Cleanup(ms.server.Close)
+	return ms
+}
+
+func (ms *mockStream) handle(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	stream := strings.HasSuffix(r.URL.Path, "Stream")
+	if stream {
+		ms.m.Lock()
+		ms.conn = conn
+		ms.m.Unlock()
+	}
+
+	for {
+		var cmd map[string]any
+		err := conn.ReadJSON(&cmd)
+		if err != nil {
+			return
+		}
+
+		if stream {
+			ms.commands <- cmd
+			continue
+		}
+
+		err = conn.WriteJSON(map[string]any{
+			"status":          true,
+			"streamSessionId": testStreamSessionID,
+			"customTag":       cmd["customTag"],
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+// send pushes a stream message with the given command and data to the client.
+func (ms *mockStream) send(command string, data any) {
+	ms.m.Lock()
+	defer ms.m.Unlock()
+
+	err := ms.conn.WriteJSON(map[string]any{
+		"command": command,
+		"data":    data,
+	})
+	if err != nil {
+		ms.t.Fatal(err)
+	}
+}
+
+// expect waits for the next command received on the stream socket and checks its name. Keep alive and ping commands are skipped unless they are the expected ones.
+func (ms *mockStream) expect(command string) map[string]any {
+	for {
+		select {
+		case cmd := <-ms.commands:
+			if cmd["command"] == command {
+				return cmd
+			}
+
+			switch cmd["command"] {
+			case "getKeepAlive", "stopKeepAlive", "ping":
+				continue
+			}
+
+			ms.t.Fatalf("expected command %q, got %v", command, cmd)
+			return nil
+		case <-time.After(5 * time.Second):
+			ms.t.Fatalf("timed out waiting for command %q", command)
+			return nil
+		}
+	}
+}
+
+func (ms *mockStream) login(ctx context.Context) *xapi.Client {
+	c, err := xapi.NewClient(ctx, xapi.WithUserCredentials(-1, ""), xapi.WithURL(ms.url))
+	if err != nil {
+		ms.t.Fatal(err)
+	}
+	ms.t.Cleanup(c.Close)
+
+	err = c.Login()
+	if err != nil {
+		ms.t.Fatal(err)
+	}
+
+	return c
+}
+
+func (ms *mockStream) connect(ctx context.Context) *xapi.StreamClient {
+	s, err := ms.login(ctx).Stream(ctx)
+	if err != nil {
+		ms.t.Fatal(err)
+	}
+	ms.t.Cleanup(s.Close)
+	return s
+}
+
+func TestStreamURLFollowsURL(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithUserCredentials(-1, ""), xapi.WithMode(xapi.ClientModeDemo), xapi.WithURL(ms.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := c.Stream(context.Background(), xapi.WithKeepAliveTimeout(0))
+	if err != nil {
+		t.Fatalf("expected the stream address to be derived from the url, got %v", err)
+	}
+	s.Close()
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v, ok := <-ch:
+		if !ok {
+			t.Fatal("channel closed")
+		}
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for stream message")
+	}
+
+	var v T
+	return v
+}
+
+func TestStreamRequiresLogin(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+
+	c, err := xapi.NewClient(context.Background(), xapi.WithURL(ms.url))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.Stream(context.Background())
+	if err == nil {
+		t.Error("expected an error before login")
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	s.Close()
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream not closed")
+	}
+
+	if s.Err() != xapi.ErrStreamClosed {
+		t.Errorf("expected ErrStreamClosed, got %v", s.Err())
+	}
+}
+
+func TestSubscribeTickPrices(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks, err := s.SubscribeTickPrices(ctx, "EURUSD", 200*time.Millisecond, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := ms.expect("getTickPrices")
+	if cmd["streamSessionId"] != testStreamSessionID || cmd["symbol"] != "EURUSD" || cmd["minArrivalTime"] != 200.0 || cmd["maxLevel"] != 0.0 {
+		t.Errorf("unexpected getTickPrices command: %v", cmd)
+	}
+
+	ms.send("tickPrices", map[string]any{"symbol": "GBPUSD", "ask": 1.3, "timestamp": 1})
+	ms.send("tickPrices", map[string]any{"symbol": "EURUSD", "ask": 1.1, "bid": 1.09, "timestamp": 1733011200000})
+
+	tick := receive(t, ticks)
+	if tick.Symbol != "EURUSD" || tick.Ask != 1.1 || tick.Bid != 1.09 || !tick.Timestamp.Equal(time.UnixMilli(1733011200000)) {
+		t.Errorf("unexpected tick: %+v", tick)
+	}
+
+	cancel()
+	cmd = ms.expect("stopTickPrices")
+	if cmd["symbol"] != "EURUSD" {
+		t.Errorf("unexpected stopTickPrices command: %v", cmd)
+	}
+}
+
+func TestSubscribeCandles(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	candles, err := s.SubscribeCandles(ctx, "EURUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := ms.expect("getCandles")
+	if cmd["streamSessionId"] != testStreamSessionID || cmd["symbol"] != "EURUSD" {
+		t.Errorf("unexpected getCandles command: %v", cmd)
+	}
+
+	ms.send("candle", map[string]any{"symbol": "EURUSD", "open": 1.05, "high": 1.06, "low": 1.04, "close": 1.055, "vol": 12.0, "ctm": 1733011200000, "ctmString": "Dec 1, 2024, 1:00:00 AM"})
+
+	candle := receive(t, candles)
+	if candle.Open != 1.05 || candle.High != 1.06 || candle.Low != 1.04 || candle.Close != 1.055 || candle.Volume != 12.0 || !candle.CandleStartTime.Equal(time.UnixMilli(1733011200000)) {
+		t.Errorf("unexpected candle: %+v", candle)
+	}
+
+	cancel()
+	ms.expect("stopCandles")
+}
+
+func TestSubscribeTrades(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trades, err := s.SubscribeTrades(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := ms.expect("getTrades")
+	if cmd["streamSessionId"] != testStreamSessionID {
+		t.Errorf("unexpected getTrades command: %v", cmd)
+	}
+
+	ms.send("trade", map[string]any{"order": 7, "position": 7, "symbol": "EURUSD", "cmd": 0, "volume": 0.1, "open_price": 1.05, "open_time": 1733011200000, "state": "Modified", "type": 0})
+
+	trade := receive(t, trades)
+	if trade.OrderID != 7 || trade.Position != 7 || *trade.Symbol != "EURUSD" || trade.Volume != 0.1 || trade.State != xapi.TradeStateModified || trade.Type != xapi.OrderTypeOpen || !trade.OpenTime.Equal(time.UnixMilli(1733011200000)) {
+		t.Errorf("unexpected trade: %+v", trade)
+	}
+
+	cancel()
+	ms.expect("stopTrades")
+}
+
+func TestSubscribeTradeStatus(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	statuses, err := s.SubscribeTradeStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getTradeStatus")
+
+	ms.send("tradeStatus", map[string]any{"order": 42, "price": 1.05, "requestStatus": 3, "customComment": "c"})
+	status := receive(t, statuses)
+	if status.OrderID != 42 || status.Price != 1.05 || status.RequestStatus != int(xapi.TradeStatusAccepted) || status.CustomComment != "c" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	cancel()
+	ms.expect("stopTradeStatus")
+}
+
+func TestWatchTradeStatuses(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w, err := s.WatchTradeStatuses(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getTradeStatus")
+
+	// The status arrives before anyone awaits it, e.g. right after CreateTradeTransaction returned.
+	ms.send("tradeStatus", map[string]any{"order": 42, "requestStatus": 3})
+	status, err := w.Await(ctx, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.OrderID != 42 || status.RequestStatus != int(xapi.TradeStatusAccepted) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	timeout, cancelTimeout := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelTimeout()
+	_, err = w.Await(timeout, 42)
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected a status to be returned once, got %v", err)
+	}
+
+	done := make(chan xapi.TradeTransactionStatus)
+	go func() {
+		status, err := w.Await(ctx, 43)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- status
+	}()
+
+	ms.send("tradeStatus", map[string]any{"order": 43, "requestStatus": 2})
+	ms.send("tradeStatus", map[string]any{"order": 43, "requestStatus": 4})
+	status = receive(t, done)
+	if status.RequestStatus != int(xapi.TradeStatusRejected) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	errs := make(chan error)
+	go func() {
+		_, err := w.Await(ctx, 44)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	w.Close()
+	ms.expect("stopTradeStatus")
+	if err := receive(t, errs); err != xapi.ErrStreamClosed {
+		t.Errorf("expected ErrStreamClosed, got %v", err)
+	}
+}
+
+func TestAwaitTradeStatus(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	done := make(chan xapi.TradeTransactionStatus)
+	go func() {
+		status, err := s.AwaitTradeStatus(context.Background(), 43)
+		if err != nil {
+			t.Error(err)
+		}
+		done <- status
+	}()
+	ms.expect("getTradeStatus")
+
+	ms.send("tradeStatus", map[string]any{"order": 43, "requestStatus": 3})
+	status := receive(t, done)
+	if status.RequestStatus != int(xapi.TradeStatusAccepted) {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	ms.expect("stopTradeStatus")
+}
+
+func TestSubscribeBalance(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	balances, err := s.SubscribeBalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getBalance")
+
+	ms.send("balance", map[string]any{"balance": 1000.0, "credit": 0.0, "equity": 1010.0, "margin": 50.0, "marginFree": 960.0, "marginLevel": 2020.0})
+
+	balance := receive(t, balances)
+	if balance.Balance != 1000.0 || balance.Equity != 1010.0 || balance.Margin != 50.0 || balance.MarginFree != 960.0 || balance.MarginLevel != 2020.0 {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+
+	cancel()
+	ms.expect("stopBalance")
+}
+
+func TestSubscribeProfits(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	profits, err := s.SubscribeProfits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getProfits")
+
+	ms.send("profit", map[string]any{"order": 7, "order2": 8, "position": 7, "profit": -1.5})
+
+	profit := receive(t, profits)
+	if profit.OrderID != 7 || profit.Order2ID != 8 || profit.Position != 7 || profit.Profit != -1.5 {
+		t.Errorf("unexpected profit: %+v", profit)
+	}
+
+	cancel()
+	ms.expect("stopProfits")
+}
+
+func TestSubscribeNews(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	s := ms.connect(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	news, err := s.SubscribeNews(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getNews")
+
+	ms.send("news", map[string]any{"key": "1f6da766abd29927aa854823f0105c23", "title": "Breaking trend", "body": "<html></html>", "time": 1733011200000})
+
+	topic := receive(t, news)
+	if topic.Key != "1f6da766abd29927aa854823f0105c23" || topic.Title != "Breaking trend" || topic.Body != "<html></html>" || topic.BodyLength != 13 || !topic.Time.Equal(time.UnixMilli(1733011200000)) {
+		t.Errorf("unexpected news topic: %+v", topic)
+	}
+
+	cancel()
+	ms.expect("stopNews")
+}
+
+func TestStreamKeepAlive(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+
+	s, err := ms.login(context.Background()).Stream(context.Background(), xapi.WithStreamPingInterval(10*time.Millisecond), xapi.WithKeepAliveTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cmd := ms.expect("getKeepAlive")
+	if cmd["streamSessionId"] != testStreamSessionID {
+		t.Errorf("unexpected getKeepAlive command: %v", cmd)
+	}
+
+	cmd = ms.expect("ping")
+	if cmd["streamSessionId"] != testStreamSessionID {
+		t.Errorf("unexpected ping command: %v", cmd)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keepAlives, err := s.SubscribeKeepAlive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ms.send("keepAlive", map[string]any{"timestamp": 1733011200000})
+	keepAlive := receive(t, keepAlives)
+	if !keepAlive.Equal(time.UnixMilli(1733011200000)) {
+		t.Errorf("unexpected keep alive: %v", keepAlive)
+	}
+
+	select {
+	case <-s.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale stream not closed")
+	}
+
+	if s.Err() != xapi.ErrStreamStale {
+		t.Errorf("expected ErrStreamStale, got %v", s.Err())
+	}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	t.Parallel()
+	ms := newMockStream(t)
+	c := ms.login(context.Background())
+
+	s, err := c.Stream(context.Background(), xapi.WithKeepAliveTimeout(200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Never read.
+	_, err = s.SubscribeTickPrices(context.Background(), "EURUSD", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getTickPrices")
+
+	balances, err := s.SubscribeBalance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.expect("getBalance")
+
+	for i := range 500 {
+		ms.send("tickPrices", map[string]any{"symbol": "EURUSD", "ask": 1.1, "timestamp": i})
+	}
+	ms.send("balance", map[string]any{"balance": 1000.0})
+
+	balance := receive(t, balances)
+	if balance.Balance != 1000 {
+		t.Errorf("unexpected balance: %+v", balance)
+	}
+	if s.Dropped() == 0 {
+		t.Error("expected the dropped tick prices to be counted")
+	}
+
+	for range 5 {
+		ms.send("keepAlive", map[string]any{"timestamp": time.Now().UnixMilli()})
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if err := s.Err(); err != nil {
+		t.Errorf("expected the stream to stay up, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package xapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Violation is a single reason why an order would be rejected.
+type Violation struct {
+	Field   string // Name of the TradeTransactionInput field, e.g. "Volume"
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Message
+}
+
+// ValidationError is returned by OrderValidator.Validate for an order which breaks the rules of its symbol.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.String()
+	}
+
+	return "invalid order: " + strings.Join(messages, "; ")
+}
+
+// OrderValidator checks orders against the metadata of a symbol before they are sent, so that invalid volumes, prices and stops are found without a round trip.
+type OrderValidator struct {
+	Symbol   Symbol
+	StepRule *StepRule // Price steps of DMA symbols, nil if not applicable
+}
+
+// NewOrderValidator returns a validator for symbol, using the rule with the symbol's StepRuleID from stepRules, if any.
+func NewOrderValidator(symbol Symbol, stepRules []StepRule) OrderValidator {
+	v := OrderValidator{Symbol: symbol}
+	for i := range stepRules {
+		if stepRules[i].ID == symbol.StepRuleID {
+			v.StepRule = &stepRules[i]
+			break
+		}
+	}
+
+	return v
+}
+
+// GetOrderValidator fetches the symbol and the step rules and returns a validator for the symbol.
+func (c *Client) GetOrderValidator(symbol string) (OrderValidator, error) {
+	return c.GetOrderValidatorContext(context.Background(), symbol)
+}
+
+// GetOrderValidatorContext is like GetOrderValidator, but stops waiting for the responses when ctx is done.
+func (c *Client) GetOrderValidatorContext(ctx context.Context, symbol string) (OrderValidator, error) {
+	s, err := c.GetSymbolContext(ctx, symbol)
+	if err != nil {
+		return OrderValidator{}, err
+	}
+
+	stepRules, err := c.GetStepRulesContext(ctx)
+	if err != nil {
+		return OrderValidator{}, err
+	}
+
+	return NewOrderValidator(s, stepRules), nil
+}
+
+// Validate returns a *ValidationError listing every violation of input, or nil if none was found. Closing orders are checked for their volume only, modifications for their prices only.
+func (v OrderValidator) Validate(input TradeTransactionInput) error {
+	var violations []Violation
+	add := func(field, format string, args ...any) {
+		violations = append(violations, Violation{
+			Field:   field,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	opening := input.Type == OrderTypeOpen
+	checkVolume := opening || input.Type == OrderTypeClose
+	checkPrices := opening || input.Type == OrderTypeModify
+
+	if opening {
+		switch input.Command {
+		case BuyCommand, BuyLimitCommand, BuyStopCommand:
+		case SellCommand, SellLimitCommand, SellStopCommand:
+			if v.Symbol.LongOnly {
+				add("Command", "symbol is long only")
+			} else if !v.Symbol.ShortSelling {
+				add("Command", "short selling is not allowed")
+			}
+		default:
+			add("Command", "command %d cannot be traded", input.Command)
+		}
+	}
+
+	if checkVolume {
+		s := v.Symbol
+		if input.Volume < s.LotMin-epsilon {
+			add("Volume", "%v is below the minimum of %v", input.Volume, s.LotMin)
+		}
+		if s.LotMax > 0 && input.Volume > s.LotMax+epsilon {
+			add("Volume", "%v is above the maximum of %v", input.Volume, s.LotMax)
+		}
+		if !isMultiple(input.Volume, s.LotStep) {
+			add("Volume", "%v is not a multiple of the lot step %v", input.Volume, s.LotStep)
+		}
+	}
+
+	if checkPrices {
+		for _, p := range []struct {
+			field string
+			value float64
+		}{
+			{"Price", input.Price},
+			{"StopLoss", input.StopLoss},
+			{"TakeProfit", input.TakeProfit},
+		} {
+			if p.value == 0 {
+				continue
+			}
+
+			step := v.priceStep(p.value)
+			if !isMultiple(p.value, step) {
+				add(p.field, "%v is not a multiple of the price step %v", p.value, step)
+			}
+		}
+
+		v.validateStops(input, add)
+
+		if input.Offset != 0 && !v.Symbol.TrailingEnabled {
+			add("Offset", "trailing stop is not enabled for the symbol")
+		}
+	}
+
+	if opening && isPending(input.Command) && !v.Symbol.Expiration.IsZero() && input.Expiration.After(v.Symbol.Expiration) {
+		add("Expiration", "%v is after the expiration of the symbol at %v", input.Expiration, v.Symbol.Expiration)
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+// validateStops checks that the price of a pending order and the stops are at least StopsLevel pips away from the market price and the order price, and that the stops are on the right side. The stops of an open position are measured from the price it would be closed at.
+func (v OrderValidator) validateStops(input TradeTransactionInput, add func(field, format string, args ...any)) {
+	buy := isBuy(input.Command)
+	minDistance := float64(v.Symbol.StopsLevel) * math.Pow10(-v.Symbol.PipsPrecision)
+
+	// A buy order is opened at the ask price and a sell order at the bid price.
+	market := v.Symbol.Bid
+	if buy {
+		market = v.Symbol.Ask
+	}
+
+	pending := isPending(input.Command)
+	if pending && input.Price != 0 && market != 0 && math.Abs(input.Price-market) < minDistance-epsilon {
+		add("Price", "%v is closer than %d pips to the market price %v", input.Price, v.Symbol.StopsLevel, market)
+	}
+
+	price := input.Price
+	if input.Type == OrderTypeModify && !pending {
+		// A long position is closed at the bid price and a short one at the ask price.
+		price = v.Symbol.Ask
+		if buy {
+			price = v.Symbol.Bid
+		}
+	} else if price == 0 {
+		price = market
+	}
+	if price == 0 {
+		return
+	}
+
+	check := func(field string, stop float64, below bool) {
+		if stop == 0 {
+			return
+		}
+
+		distance := price - stop
+		if !below {
+			distance = -distance
+		}
+
+		if distance <= 0 {
+			side := "above"
+			if below {
+				side = "below"
+			}
+			add(field, "%v must be %s the price %v", stop, side, price)
+		} else if distance < minDistance-epsilon {
+			add(field, "%v is closer than %d pips to the price %v", stop, v.Symbol.StopsLevel, price)
+		}
+	}
+
+	check("StopLoss", input.StopLoss, buy)
+	check("TakeProfit", input.TakeProfit, !buy)
+}
+
+// Round returns input with its volume rounded to the nearest lot step within the lot limits and its prices rounded to the nearest price step.
+func (v OrderValidator) Round(input TradeTransactionInput) TradeTransactionInput {
+	s := v.Symbol
+	if input.Volume != 0 {
+		input.Volume = roundToStep(input.Volume, s.LotStep)
+		if input.Volume < s.LotMin {
+			input.Volume = s.LotMin
+		}
+		if s.LotMax > 0 && input.Volume > s.LotMax {
+			input.Volume = s.LotMax
+		}
+	}
+
+	input.Price = v.RoundPrice(input.Price)
+	input.StopLoss = v.RoundPrice(input.StopLoss)
+	input.TakeProfit = v.RoundPrice(input.TakeProfit)
+	return input
+}
+
+// RoundPrice rounds price to the nearest valid price of the symbol.
+func (v OrderValidator) RoundPrice(price float64) float64 {
+	if price == 0 {
+		return 0
+	}
+
+	return roundToStep(price, v.priceStep(price))
+}
+
+// priceStep returns the step rule step applying to price, or the smallest price change allowed by Precision.
+func (v OrderValidator) priceStep(price float64) float64 {
+	step := math.Pow10(-v.Symbol.Precision)
+	if v.StepRule != nil {
+		from := math.Inf(-1)
+		for _, s := range v.StepRule.Steps {
+			if price >= s.FromValue && s.FromValue > from && s.Step > 0 {
+				step = s.Step
+				from = s.FromValue
+			}
+		}
+	}
+
+	return step
+}
+
+func isBuy(command TradeCommand) bool {
+	return command == BuyCommand || command == BuyLimitCommand || command == BuyStopCommand
+}
+
+func isPending(command TradeCommand) bool {
+	return command != BuyCommand && command != SellCommand
+}
+
+// epsilon absorbs floating point errors of values which are exact in decimal.
+const epsilon = 1e-9
+
+func isMultiple(value, step float64) bool {
+	if step <= 0 {
+		return true
+	}
+
+	n := value / step
+	return math.Abs(n-math.Round(n)) < 1e-6
+}
+
+func roundToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+
+	rounded := math.Round(value/step) * step
+
+	// Drop the floating point noise of the multiplication, e.g. 0.30000000000000004.
+	decimals := max(0, int(math.Ceil(-math.Log10(step)))+1)
+	scale := math.Pow10(decimals)
+	return math.Round(rounded*scale) / scale
+}
@@ -0,0 +1,139 @@
+package xapi_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+)
+
+var eurusd = xapi.Symbol{
+	Ask:           1.08012,
+	Bid:           1.08,
+	LotMin:        0.01,
+	LotMax:        100,
+	LotStep:       0.01,
+	Precision:     5,
+	PipsPrecision: 4,
+	StopsLevel:    10,
+	ShortSelling:  true,
+}
+
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+
+	var validationErr *xapi.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	var fields []string
+	for _, v := range validationErr.Violations {
+		fields = append(fields, v.Field)
+	}
+	return fields
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	expiring := eurusd
+	expiring.Expiration = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	longOnly := eurusd
+	longOnly.LongOnly = true
+
+	tests := []struct {
+		name   string
+		symbol xapi.Symbol
+		input  xapi.TradeTransactionInput
+		fields []string
+	}{
+		{"valid buy", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 0.1, StopLoss: 1.07, TakeProfit: 1.09}, nil},
+		{"volume too low", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 0.001}, []string{"Volume", "Volume"}},
+		{"volume too high", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 101}, []string{"Volume"}},
+		{"volume off step", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 0.015}, []string{"Volume"}},
+		{"price precision", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyLimitCommand, Volume: 1, Price: 1.070001}, []string{"Price"}},
+		{"stop loss above buy", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 1, StopLoss: 1.09}, []string{"StopLoss"}},
+		{"take profit too close", eurusd, xapi.TradeTransactionInput{Command: xapi.SellCommand, Volume: 1, TakeProfit: 1.0795}, []string{"TakeProfit"}},
+		{"long only", longOnly, xapi.TradeTransactionInput{Command: xapi.SellCommand, Volume: 1}, []string{"Command"}},
+		{"trailing disabled", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyCommand, Volume: 1, Offset: 10}, []string{"Offset"}},
+		{"expiration", expiring, xapi.TradeTransactionInput{Command: xapi.BuyLimitCommand, Volume: 1, Price: 1.07, Expiration: expiring.Expiration.Add(time.Hour)}, []string{"Expiration"}},
+		{"close checks volume only", eurusd, xapi.TradeTransactionInput{Type: xapi.OrderTypeClose, Volume: 0.1, StopLoss: 2}, nil},
+		{"modify checks prices only", eurusd, xapi.TradeTransactionInput{Type: xapi.OrderTypeModify, Command: xapi.BuyCommand, StopLoss: 1.0799}, []string{"StopLoss"}},
+		{"stop loss above open price", eurusd, xapi.TradeTransactionInput{Type: xapi.OrderTypeModify, Command: xapi.BuyCommand, Price: 1.05, StopLoss: 1.07}, nil},
+		{"stop loss below short open price", eurusd, xapi.TradeTransactionInput{Type: xapi.OrderTypeModify, Command: xapi.SellCommand, Price: 1.09, StopLoss: 1.085}, nil},
+		{"pending too close", eurusd, xapi.TradeTransactionInput{Command: xapi.BuyStopCommand, Volume: 1, Price: 1.0805}, []string{"Price"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := xapi.NewOrderValidator(test.symbol, nil).Validate(test.input)
+			fields := violatedFields(t, err)
+			if len(fields) != len(test.fields) {
+				t.Fatalf("expected violations of %v, got %v", test.fields, err)
+			}
+			for i := range fields {
+				if fields[i] != test.fields[i] {
+					t.Errorf("expected violations of %v, got %v", test.fields, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateStepRule(t *testing.T) {
+	t.Parallel()
+
+	symbol := eurusd
+	symbol.StepRuleID = 2
+	v := xapi.NewOrderValidator(symbol, []xapi.StepRule{
+		{ID: 1, Steps: []xapi.Step{{FromValue: 0, Step: 0.1}}},
+		{ID: 2, Steps: []xapi.Step{{FromValue: 10, Step: 0.05}, {FromValue: 0, Step: 0.01}}},
+	})
+
+	if v.StepRule == nil || v.StepRule.ID != 2 {
+		t.Fatalf("expected step rule 2, got %v", v.StepRule)
+	}
+
+	err := v.Validate(xapi.TradeTransactionInput{Command: xapi.BuyLimitCommand, Volume: 1, Price: 12.02})
+	if fields := violatedFields(t, err); len(fields) != 1 || fields[0] != "Price" {
+		t.Errorf("expected a price violation, got %v", err)
+	}
+
+	if price := v.RoundPrice(12.02); price != 12 {
+		t.Errorf("expected 12, got %v", price)
+	}
+	if price := v.RoundPrice(5.018); price != 5.02 {
+		t.Errorf("expected 5.02, got %v", price)
+	}
+}
+
+func TestRound(t *testing.T) {
+	t.Parallel()
+
+	v := xapi.NewOrderValidator(eurusd, nil)
+	input := v.Round(xapi.TradeTransactionInput{
+		Command:    xapi.BuyLimitCommand,
+		Volume:     0.297,
+		Price:      1.0700049,
+		StopLoss:   1.0600001,
+		TakeProfit: 1.08,
+	})
+
+	if input.Volume != 0.3 || input.Price != 1.07 || input.StopLoss != 1.06 || input.TakeProfit != 1.08 {
+		t.Errorf("unexpected rounding %+v", input)
+	}
+
+	err := v.Validate(input)
+	if err != nil {
+		t.Errorf("expected the rounded order to be valid, got %v", err)
+	}
+
+	if volume := v.Round(xapi.TradeTransactionInput{Volume: 0.001}).Volume; volume != 0.01 {
+		t.Errorf("expected the volume to be raised to the minimum, got %v", volume)
+	}
+}
@@ -0,0 +1,350 @@
+// Package xapitest provides an in-process xAPI server for testing code built on the xapi package.
+package xapitest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/voxelost/xapi"
+)
+
+// StreamSessionID is the stream session id returned by a successful login.
+const StreamSessionID = "xapitest-stream-session"
+
+// Version is returned by the default getVersion handler.
+const Version = "2.5.0"
+
+// ErrDisconnect makes the server close the connection instead of answering, when returned by a handler.
+var ErrDisconnect = errors.New("xapitest: disconnect")
+
+// Request is a command received by the server.
+type Request struct {
+	Command         string
+	Arguments       json.RawMessage
+	CustomTag       string
+	StreamSessionID string // Only set for commands received on the stream socket
+}
+
+// Decode unmarshals the arguments of the command into v.
+func (r Request) Decode(v any) error {
+	if r.Arguments == nil {
+		return nil
+	}
+
+	return json.Unmarshal(r.Arguments, v)
+}
+
+// HandlerFunc answers a command. The returned data is sent as returnData. An xapi.ApiError is sent as an error response with its code, any other error with the code of xapi.ErrInternal.
+type HandlerFunc func(req Request) (any, error)
+
+// Server speaks the xAPI command protocol on URL and the streaming protocol on StreamURL. Every command except login requires the connection to be logged in. getVersion, getServerTime, ping and logout are answered by default, any other command must be registered with Handle or Respond.
+type Server struct {
+	URL       string // Address of the command socket, to be used with xapi.WithURL
+	StreamURL string // Address of the streaming socket, derived from URL the same way as xapi does
+
+	server *httptest.Server
+
+	m              sync.Mutex
+	handlers       map[string]HandlerFunc
+	credentials    *xapi.Credentials
+	conns          map[*conn]struct{}
+	streams        map[*conn]struct{}
+	requests       []Request
+	streamRequests []Request
+	logins         int
+}
+
+type conn struct {
+	ws *websocket.Conn
+	wm sync.Mutex // guards writes to ws
+}
+
+func (c *conn) writeJSON(v any) error {
+	c.wm.Lock()
+	defer c.wm.Unlock()
+	return c.ws.WriteJSON(v)
+}
+
+// NewServer starts a server accepting any login. It should be closed with Close.
+func NewServer() *Server {
+	s := &Server{
+		handlers: make(map[string]HandlerFunc),
+		conns:    make(map[*conn]struct{}),
+		streams:  make(map[*conn]struct{}),
+	}
+
+	s.Respond("ping", nil)
+	s.Respond("logout", nil)
+	s.Respond("getVersion", map[string]any{"version": Version})
+	s.Handle("getServerTime", func(Request) (any, error) {
+		now := time.Now()
+		return map[string]any{
+			"time":       now.UnixMilli(),
+			"timeString": now.Format("Jan 2, 2006, 3:04:05 PM"),
+		}, nil
+	})
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = "ws://" + strings.TrimPrefix(s.server.URL, "http://")
+	s.StreamURL = s.URL + "Stream"
+	return s
+}
+
+// Close disconnects every client and stops the server.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.server.Close()
+}
+
+// SetCredentials makes login fail with xapi.ErrInvalidLogin unless the given credentials are sent.
+func (s *Server) SetCredentials(userID int, password string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.credentials = &xapi.Credentials{
+		UserID:   userID,
+		Password: password,
+	}
+}
+
+// Handle registers the handler of command, replacing the previous one.
+func (s *Server) Handle(command string, handler HandlerFunc) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.handlers[command] = handler
+}
+
+// Respond answers every following command with data.
+func (s *Server) Respond(command string, data any) {
+	s.Handle(command, func(Request) (any, error) {
+		return data, nil
+	})
+}
+
+// Fail answers every following command with err.
+func (s *Server) Fail(command string, err error) {
+	s.Handle(command, func(Request) (any, error) {
+		return nil, err
+	})
+}
+
+// DisconnectOn closes the connection instead of answering the next command, once.
+func (s *Server) DisconnectOn(command string) {
+	s.m.Lock()
+	handler := s.handlers[command]
+	s.m.Unlock()
+
+	var once sync.Once
+	s.Handle(command, func(req Request) (any, error) {
+		disconnect := false
+		once.Do(func() {
+			disconnect = true
+		})
+		if disconnect {
+			return nil, ErrDisconnect
+		}
+
+		if handler == nil {
+			return nil, xapi.ErrUnknownMethod
+		}
+		return handler(req)
+	})
+}
+
+// Disconnect closes every open command and stream connection.
+func (s *Server) Disconnect() {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	for c := range s.conns {
+		c.ws.Close()
+	}
+	for c := range s.streams {
+		c.ws.Close()
+	}
+}
+
+// Publish sends a stream message to every open stream connection.
+func (s *Server) Publish(command string, data any) error {
+	s.m.Lock()
+	streams := make([]*conn, 0, len(s.streams))
+	for c := range s.streams {
+		streams = append(streams, c)
+	}
+	s.m.Unlock()
+
+	var errs []error
+	for _, c := range streams {
+		err := c.writeJSON(map[string]any{
+			"command": command,
+			"data":    data,
+		})
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Requests returns the commands received on the command socket so far.
+func (s *Server) Requests() []Request {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// StreamRequests returns the commands received on the stream socket so far.
+func (s *Server) StreamRequests() []Request {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]Request(nil), s.streamRequests...)
+}
+
+// Logins returns the number of successful logins.
+func (s *Server) Logins() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.logins
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := &conn{ws: ws}
+	stream := strings.HasSuffix(r.URL.Path, "Stream")
+
+	s.m.Lock()
+	if stream {
+		s.streams[c] = struct{}{}
+	} else {
+		s.conns[c] = struct{}{}
+	}
+	s.m.Unlock()
+
+	defer func() {
+		s.m.Lock()
+		delete(s.conns, c)
+		delete(s.streams, c)
+		s.m.Unlock()
+		ws.Close()
+	}()
+
+	if stream {
+		s.readStream(c)
+	} else {
+		s.serve(c)
+	}
+}
+
+type message struct {
+	Command         string          `json:"command"`
+	Arguments       json.RawMessage `json:"arguments,omitempty"`
+	CustomTag       string          `json:"customTag,omitempty"`
+	StreamSessionID string          `json:"streamSessionId,omitempty"`
+}
+
+func (s *Server) readStream(c *conn) {
+	for {
+		var msg message
+		err := c.ws.ReadJSON(&msg)
+		if err != nil {
+			return
+		}
+
+		s.m.Lock()
+		s.streamRequests = append(s.streamRequests, Request(msg))
+		s.m.Unlock()
+	}
+}
+
+func (s *Server) serve(c *conn) {
+	loggedIn := false
+	for {
+		var msg message
+		err := c.ws.ReadJSON(&msg)
+		if err != nil {
+			return
+		}
+
+		req := Request(msg)
+		s.m.Lock()
+		s.requests = append(s.requests, req)
+		handler := s.handlers[req.Command]
+		s.m.Unlock()
+
+		resp := map[string]any{
+			"status": true,
+		}
+		if req.CustomTag != "" {
+			resp["customTag"] = req.CustomTag
+		}
+
+		var data any
+		switch {
+		case req.Command == "login":
+			err = s.login(req)
+			if err == nil {
+				loggedIn = true
+				resp["streamSessionId"] = StreamSessionID
+			}
+		case !loggedIn:
+			err = xapi.ErrNotLoggedIn
+		case handler == nil:
+			err = xapi.ErrUnknownMethod
+		default:
+			data, err = handler(req)
+			if req.Command == "logout" && err == nil {
+				loggedIn = false
+			}
+		}
+
+		if errors.Is(err, ErrDisconnect) {
+			return
+		}
+
+		if err != nil {
+			var apiErr xapi.ApiError
+			if !errors.As(err, &apiErr) {
+				apiErr = xapi.ApiError{Code: xapi.ErrInternal.Code, Message: err.Error()}
+			}
+
+			resp["status"] = false
+			resp["errorCode"] = apiErr.Code
+			resp["errorDescr"] = apiErr.Message
+		} else if data != nil {
+			resp["returnData"] = data
+		}
+
+		err = c.writeJSON(resp)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) login(req Request) error {
+	var creds xapi.Credentials
+	err := req.Decode(&creds)
+	if err != nil {
+		return xapi.ErrInvalidParameters
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.credentials != nil && creds != *s.credentials {
+		return xapi.ErrInvalidLogin
+	}
+
+	s.logins++
+	return nil
+}
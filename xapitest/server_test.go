@@ -0,0 +1,182 @@
+package xapitest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/voxelost/xapi"
+	"github.com/voxelost/xapi/xapitest"
+)
+
+func newClient(t *testing.T, server *xapitest.Server) *xapi.Client {
+	t.Helper()
+
+	c, err := xapi.NewClient(context.Background(),
+		xapi.WithURL(server.URL),
+		xapi.WithUserCredentials(42, "secret"),
+		xapi.WithReconnect(xapi.ReconnectPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+	server := xapitest.NewServer()
+	defer server.Close()
+	server.SetCredentials(42, "other")
+
+	c := newClient(t, server)
+	_, err := c.GetVersion()
+	if !errors.Is(err, xapi.ErrNotLoggedIn) {
+		t.Errorf("expected ErrNotLoggedIn, got %v", err)
+	}
+
+	err = c.Login()
+	if !errors.Is(err, xapi.ErrInvalidLogin) {
+		t.Errorf("expected ErrInvalidLogin, got %v", err)
+	}
+
+	server.SetCredentials(42, "secret")
+	err = c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, err := c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != xapitest.Version {
+		t.Errorf("expected version %q, got %q", xapitest.Version, version)
+	}
+	if server.Logins() != 1 {
+		t.Errorf("expected 1 login, got %d", server.Logins())
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	t.Parallel()
+	server := xapitest.NewServer()
+	defer server.Close()
+
+	server.Handle("getMarginTrade", func(req xapitest.Request) (any, error) {
+		var args struct {
+			Symbol string  `json:"symbol"`
+			Volume float64 `json:"volume"`
+		}
+		err := req.Decode(&args)
+		if err != nil {
+			return nil, err
+		}
+
+		return map[string]any{"margin": args.Volume * 100}, nil
+	})
+	server.Fail("getCommissionDef", xapi.ErrSymbolNotFound)
+
+	c := newClient(t, server)
+	err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	margin, err := c.GetMarginTrade("EURUSD", 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if margin != 50 {
+		t.Errorf("expected margin 50, got %v", margin)
+	}
+
+	_, err = c.GetCommissionDef("EURUSD", 1)
+	if !errors.Is(err, xapi.ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+
+	_, err = c.GetCalendar()
+	if !errors.Is(err, xapi.ErrUnknownMethod) {
+		t.Errorf("expected ErrUnknownMethod, got %v", err)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 4 || requests[1].Command != "getMarginTrade" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestDisconnectOn(t *testing.T) {
+	t.Parallel()
+	server := xapitest.NewServer()
+	defer server.Close()
+	server.DisconnectOn("getVersion")
+
+	c := newClient(t, server)
+	err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.GetVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server.Logins() != 2 {
+		t.Errorf("expected the client to log in again, got %d logins", server.Logins())
+	}
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+	server := xapitest.NewServer()
+	defer server.Close()
+
+	c := newClient(t, server)
+	err := c.Login()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := c.Stream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	balances, err := s.SubscribeBalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stream connection may not be registered by the server yet, so publish until the balance arrives.
+	timeout := time.After(5 * time.Second)
+	for {
+		err = server.Publish("balance", map[string]any{"balance": 1000.0})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case balance := <-balances:
+			if balance.Balance != 1000 {
+				t.Errorf("expected balance 1000, got %v", balance.Balance)
+			}
+
+			for _, req := range server.StreamRequests() {
+				if req.Command == "getBalance" && req.StreamSessionID != xapitest.StreamSessionID {
+					t.Errorf("expected stream session id %q, got %q", xapitest.StreamSessionID, req.StreamSessionID)
+				}
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("timed out waiting for the balance")
+		}
+	}
+}